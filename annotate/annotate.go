@@ -0,0 +1,139 @@
+// Annotates kmers with their positions on reference sequences.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/fluhus/biostuff/sequtil"
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/sets"
+	"github.com/fluhus/kwas/gff"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+)
+
+var (
+	inFile  = flag.String("i", "", "Input kmer file (text lines, or dump with -d)")
+	dump    = flag.Bool("d", false, "Input is a sorted dump file")
+	idxFile = flag.String("x", "", "Reference index file, created by refidx")
+	gffFile = flag.String("g", "", "Optional GFF file of the references")
+	types   = flag.String("t", "gene",
+		"Comma-separated GFF feature types to report")
+	outFile = flag.String("o", "", "Output TSV file")
+)
+
+func main() {
+	util.Die(parseArgs())
+
+	var idx *gff.Index
+	if *gffFile != "" {
+		fmt.Println("Reading GFF")
+		var err error
+		idx, err = loadGFF(*gffFile, strings.Split(*types, ","))
+		util.Die(err)
+	}
+
+	kmers := kmr.IterKmersFile(*inFile)
+	if !*dump {
+		fmt.Println("Reading kmers")
+		k, err := kmr.ReadKmersList(*inFile, false)
+		util.Die(err)
+		slices.SortFunc(k, kmr.Kmer.Compare)
+		k = slices.Compact(k)
+		fmt.Println(len(k), "unique kmers")
+		kmers = func(yield func(kmr.Kmer, error) bool) {
+			for _, kmer := range k {
+				if !yield(kmer, nil) {
+					return
+				}
+			}
+		}
+	}
+
+	fmt.Println("Writing to:", *outFile)
+	fout, err := aio.Create(*outFile)
+	util.Die(err)
+	w := bufio.NewWriter(fout)
+	util.Die(annotate(w, kmers, idx))
+	util.Die(w.Flush())
+	util.Die(fout.Close())
+	fmt.Println("Done")
+}
+
+// Writes a TSV line for each reference hit of each kmer, or a line with empty
+// fields for kmers with no hits.
+func annotate(w *bufio.Writer, kmers iter.Seq2[kmr.Kmer, error],
+	idx *gff.Index) error {
+	fmt.Fprintln(w, "kmer\tcontig\tstart\tstrand\tfeatures")
+	nkmers, nfound := 0, 0
+	refs := kmr.IterTuplesFile[kmr.RefHandler](*idxFile)
+	for j, err := range kmr.Join(kmers, refs,
+		func(k kmr.Kmer) kmr.Kmer { return k },
+		func(t *kmr.RefTuple) kmr.Kmer { return t.Kmer }, kmr.LeftJoin) {
+		if err != nil {
+			return err
+		}
+		nkmers++
+		kmer := string(sequtil.DNAFrom2Bit(nil, j.Left[:])[:kmr.K])
+		if !j.Found {
+			fmt.Fprintf(w, "%s\t\t\t\t\n", kmer)
+			continue
+		}
+		nfound++
+		for _, hit := range j.Right.Data.Hits {
+			strand := "+"
+			if hit.Rev {
+				strand = "-"
+			}
+			var names []string
+			if idx != nil {
+				for _, f := range idx.Overlapping(hit.Contig, hit.Pos) {
+					names = append(names, f.Name())
+				}
+			}
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", kmer,
+				hit.Contig, hit.Pos, strand,
+				strings.Join(names, ",")); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Println(nfound, "out of", nkmers, "kmers found on the references")
+	return nil
+}
+
+// Reads the GFF features of the given types, indexed for kmer queries.
+func loadGFF(file string, types []string) (*gff.Index, error) {
+	typs := sets.Set[string]{}.Add(types...)
+	var features []*gff.Feature
+	for f, err := range gff.IterFile(file) {
+		if err != nil {
+			return nil, err
+		}
+		if typs.Has(f.Type) {
+			features = append(features, f)
+		}
+	}
+	fmt.Println(len(features), "features")
+	return gff.NewIndex(features, kmr.K), nil
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *inFile == "" {
+		return fmt.Errorf("empty input path")
+	}
+	if *idxFile == "" {
+		return fmt.Errorf("empty index path")
+	}
+	if *outFile == "" {
+		return fmt.Errorf("empty output path")
+	}
+	return nil
+}
@@ -0,0 +1,371 @@
+// Package bam decodes BAM files into SAM records, with no external
+// dependencies.
+//
+// This package uses the format described in:
+// https://samtools.github.io/hts-specs/SAMv1.pdf
+package bam
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fluhus/biostuff/formats/sam"
+	"github.com/fluhus/kwas/util"
+)
+
+// Magic bytes at the start of a decompressed BAM stream.
+const magic = "BAM\x01"
+
+// IsBAM returns whether b starts with the magic bytes of a decompressed BAM
+// stream.
+func IsBAM(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(magic))
+}
+
+// A Reader reads SAM records from a decompressed BAM stream.
+type Reader struct {
+	r      *bufio.Reader
+	Header string   // Header text, in SAM format.
+	Refs   []string // Reference sequence names.
+	buf    []byte
+}
+
+// NewReader returns a reader that reads from the given decompressed BAM
+// stream, after reading its header.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	m := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, m); err != nil {
+		return nil, fmt.Errorf("bad BAM magic: %w", util.NotExpectingEOF(err))
+	}
+	if string(m) != magic {
+		return nil, fmt.Errorf("bad BAM magic: %q, want %q", m, magic)
+	}
+	text, err := readBytes32(br)
+	if err != nil {
+		return nil, fmt.Errorf("bad BAM header: %w", err)
+	}
+	nrefs, err := readInt32(br)
+	if err != nil {
+		return nil, fmt.Errorf("bad BAM header: %w",
+			util.NotExpectingEOF(err))
+	}
+	if nrefs < 0 {
+		return nil, fmt.Errorf("bad number of references: %d", nrefs)
+	}
+	result := &Reader{r: br, Header: string(bytes.TrimRight(text, "\x00"))}
+	for range nrefs {
+		name, err := readBytes32(br)
+		if err != nil {
+			return nil, fmt.Errorf("bad BAM header: %w", err)
+		}
+		if _, err := readInt32(br); err != nil { // Reference length.
+			return nil, fmt.Errorf("bad BAM header: %w",
+				util.NotExpectingEOF(err))
+		}
+		result.Refs = append(result.Refs,
+			string(bytes.TrimRight(name, "\x00")))
+	}
+	return result, nil
+}
+
+// Read returns the next record.
+func (r *Reader) Read() (*sam.SAM, error) {
+	size, err := readInt32(r.r)
+	if err != nil {
+		return nil, err // EOF here is the end of the stream.
+	}
+	if size < 32 {
+		return nil, fmt.Errorf("bad BAM record size: %d", size)
+	}
+	if cap(r.buf) < int(size) {
+		r.buf = make([]byte, size)
+	}
+	b := r.buf[:size]
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, util.NotExpectingEOF(err)
+	}
+	return r.parseRecord(b)
+}
+
+// Parses a single record, without its size field.
+func (r *Reader) parseRecord(b []byte) (*sam.SAM, error) {
+	le := binary.LittleEndian
+	refID := int32(le.Uint32(b))
+	pos := int32(le.Uint32(b[4:]))
+	nameLen := int(b[8])
+	mapq := int(b[9])
+	ncigar := int(le.Uint16(b[12:]))
+	flag := int(le.Uint16(b[14:]))
+	nseq := int(int32(le.Uint32(b[16:])))
+	nextRefID := int32(le.Uint32(b[20:]))
+	nextPos := int32(le.Uint32(b[24:]))
+	tlen := int(int32(le.Uint32(b[28:])))
+	b = b[32:]
+
+	if nseq < 0 || len(b) < nameLen+ncigar*4+(nseq+1)/2+nseq {
+		return nil, fmt.Errorf("BAM record is too short")
+	}
+	result := &sam.SAM{
+		Flag:  flag,
+		Pos:   int(pos) + 1,
+		Mapq:  mapq,
+		Pnext: int(nextPos) + 1,
+		Tlen:  tlen,
+	}
+	var err error
+	if result.Rname, err = r.refName(refID); err != nil {
+		return nil, err
+	}
+	if result.Rnext, err = r.refName(nextRefID); err != nil {
+		return nil, err
+	}
+	if result.Rnext != "*" && nextRefID == refID {
+		result.Rnext = "="
+	}
+	result.Qname = string(bytes.TrimRight(b[:nameLen], "\x00"))
+	b = b[nameLen:]
+
+	result.Cigar = parseCigar(b[:ncigar*4])
+	b = b[ncigar*4:]
+
+	result.Seq = parseSeq(b[:(nseq+1)/2], nseq)
+	b = b[(nseq+1)/2:]
+
+	result.Qual = parseQual(b[:nseq])
+	b = b[nseq:]
+
+	if result.Tags, err = parseTags(b); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Returns the name of the given reference ID, or "*" for -1.
+func (r *Reader) refName(id int32) (string, error) {
+	if id == -1 {
+		return "*", nil
+	}
+	if id < 0 || int(id) >= len(r.Refs) {
+		return "", fmt.Errorf("bad reference ID: %d, want -1 to %d",
+			id, len(r.Refs)-1)
+	}
+	return r.Refs[id], nil
+}
+
+// Returns the textual form of a binary CIGAR.
+func parseCigar(b []byte) string {
+	if len(b) == 0 {
+		return "*"
+	}
+	var sb strings.Builder
+	for i := 0; i < len(b); i += 4 {
+		op := binary.LittleEndian.Uint32(b[i:])
+		sb.WriteString(strconv.Itoa(int(op >> 4)))
+		sb.WriteByte("MIDNSHP=X????????"[op&0xf])
+	}
+	return sb.String()
+}
+
+// Returns the textual form of a 4-bit encoded sequence of length n.
+func parseSeq(b []byte, n int) string {
+	if n == 0 {
+		return "*"
+	}
+	const bases = "=ACMGRSVTWYHKDBN"
+	s := make([]byte, n)
+	for i := range s {
+		if i%2 == 0 {
+			s[i] = bases[b[i/2]>>4]
+		} else {
+			s[i] = bases[b[i/2]&0xf]
+		}
+	}
+	return string(s)
+}
+
+// Returns the textual form of binary phred qualities.
+func parseQual(b []byte) string {
+	if len(b) == 0 || b[0] == 0xff {
+		return "*"
+	}
+	s := make([]byte, len(b))
+	for i, q := range b {
+		s[i] = q + 33
+	}
+	return string(s)
+}
+
+// Parses binary tags, typed like the tags of text SAM records.
+func parseTags(b []byte) (map[string]any, error) {
+	result := map[string]any{}
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, fmt.Errorf("bad BAM tag: %q", b)
+		}
+		name, typ := string(b[:2]), b[2]
+		b = b[3:]
+		var val any
+		var n int
+		var err error
+		switch typ {
+		case 'A':
+			if len(b) < 1 {
+				return nil, fmt.Errorf("bad BAM tag %s: too short", name)
+			}
+			val, n = b[0], 1
+		case 'Z', 'H':
+			n = bytes.IndexByte(b, 0) + 1
+			if n == 0 {
+				return nil, fmt.Errorf("bad BAM tag %s: missing NUL", name)
+			}
+			val = string(b[:n-1])
+			if typ == 'H' {
+				val, err = hex.DecodeString(string(b[:n-1]))
+			}
+		case 'B':
+			val, n, err = parseArrayTag(b)
+		default:
+			var x any
+			x, n, err = parseNumber(b, typ)
+			val = x
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad BAM tag %s: %w", name, err)
+		}
+		result[name] = val
+		b = b[n:]
+	}
+	return result, nil
+}
+
+// Sizes in bytes of numeric tag types.
+var numberSizes = map[byte]int{
+	'c': 1, 'C': 1, 's': 2, 'S': 2, 'i': 4, 'I': 4, 'f': 4,
+}
+
+// Parses a single number of the given type. Returns the number as an int or
+// a float64, and its length in bytes.
+func parseNumber(b []byte, typ byte) (any, int, error) {
+	le := binary.LittleEndian
+	n := numberSizes[typ]
+	if n == 0 {
+		return nil, 0, fmt.Errorf("unsupported type: %q", typ)
+	}
+	if len(b) < n {
+		return nil, 0, fmt.Errorf("too short")
+	}
+	switch typ {
+	case 'c':
+		return int(int8(b[0])), n, nil
+	case 'C':
+		return int(b[0]), n, nil
+	case 's':
+		return int(int16(le.Uint16(b))), n, nil
+	case 'S':
+		return int(le.Uint16(b)), n, nil
+	case 'i':
+		return int(int32(le.Uint32(b))), n, nil
+	case 'I':
+		return int(le.Uint32(b)), n, nil
+	default: // 'f'
+		return float64(math.Float32frombits(le.Uint32(b))), n, nil
+	}
+}
+
+// Parses an array tag into its textual form, like text SAM records hold it.
+// Returns the text and the tag's length in bytes.
+func parseArrayTag(b []byte) (string, int, error) {
+	if len(b) < 5 {
+		return "", 0, fmt.Errorf("too short")
+	}
+	typ := b[0]
+	count := int(binary.LittleEndian.Uint32(b[1:]))
+	var sb strings.Builder
+	sb.WriteByte(typ)
+	n := 5
+	for range count {
+		x, m, err := parseNumber(b[n:], typ)
+		if err != nil {
+			return "", 0, err
+		}
+		sb.WriteByte(',')
+		sb.WriteString(fmt.Sprint(x))
+		n += m
+	}
+	return sb.String(), n, nil
+}
+
+// Reads a little-endian int32. Returns EOF only if nothing was read.
+func readInt32(r io.Reader) (int32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return int32(binary.LittleEndian.Uint32(b[:])), nil
+}
+
+// Reads bytes prefixed by their int32 length.
+func readBytes32(r io.Reader) ([]byte, error) {
+	n, err := readInt32(r)
+	if err != nil {
+		return nil, util.NotExpectingEOF(err)
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("bad length: %d", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, util.NotExpectingEOF(err)
+	}
+	return b, nil
+}
+
+// IterFile iterates over the records of a BAM file, using n goroutines for
+// decompression.
+func IterFile(file string, n int) iter.Seq2[*sam.SAM, error] {
+	return func(yield func(*sam.SAM, error) bool) {
+		f, err := os.Open(file)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer f.Close()
+		z := NewBGZFReader(f, n)
+		defer z.Close()
+		for s, err := range IterReader(z) {
+			if !yield(s, err) {
+				return
+			}
+		}
+	}
+}
+
+// IterReader iterates over the records of a decompressed BAM stream.
+func IterReader(r io.Reader) iter.Seq2[*sam.SAM, error] {
+	return func(yield func(*sam.SAM, error) bool) {
+		br, err := NewReader(r)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for {
+			s, err := br.Read()
+			if err == io.EOF {
+				return
+			}
+			if !yield(s, err) || err != nil {
+				return
+			}
+		}
+	}
+}
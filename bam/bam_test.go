@@ -0,0 +1,269 @@
+package bam
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/fluhus/biostuff/formats/sam"
+)
+
+func TestBGZFReader(t *testing.T) {
+	data := bytes.Repeat([]byte("hello BGZF world "), 1000)
+	for _, n := range []int{1, 2, 8} {
+		r := NewBGZFReader(bytes.NewReader(toBGZF(t, data, 100)), n)
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll(n=%d) failed: %v", n, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("ReadAll(n=%d)=%q..., want %q...", n, got[:20], data[:20])
+		}
+	}
+}
+
+func TestBGZFReader_bad(t *testing.T) {
+	data := toBGZF(t, []byte("hello BGZF world"), 100)
+	badCRC := bytes.Clone(data)
+	badCRC[len(badCRC)-8-28]++ // CRC of the first block.
+	inputs := map[string][]byte{
+		"truncated": data[:len(data)-30],
+		"bad crc":   badCRC,
+		"not bgzf":  []byte("hello BGZF world, not compressed"),
+	}
+	for name, input := range inputs {
+		_, err := io.ReadAll(NewBGZFReader(bytes.NewReader(input), 2))
+		if err == nil {
+			t.Errorf("ReadAll(%s) succeeded, want error", name)
+		}
+	}
+}
+
+func TestBGZFReader_close(t *testing.T) {
+	data := bytes.Repeat([]byte("hello BGZF world "), 1000)
+	r := NewBGZFReader(bytes.NewReader(toBGZF(t, data, 10)), 2)
+	if _, err := r.Read(make([]byte, 5)); err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	r.Close() // Should not hang.
+}
+
+func TestIsBGZF(t *testing.T) {
+	data := toBGZF(t, []byte("hello"), 100)
+	if !IsBGZF(data) {
+		t.Errorf("IsBGZF(%v)=false, want true", data[:bgzfHeaderLen])
+	}
+	if IsBGZF(data[:bgzfHeaderLen-1]) {
+		t.Errorf("IsBGZF(%v)=true, want false", data[:bgzfHeaderLen-1])
+	}
+	if IsBGZF([]byte("@HD\tVN:1.6\tSO:unsorted\n")) {
+		t.Errorf("IsBGZF(SAM)=true, want false")
+	}
+}
+
+func TestIterReader(t *testing.T) {
+	want := []*sam.SAM{
+		{
+			Qname: "read1", Flag: 99, Rname: "chr2", Pos: 101, Mapq: 60,
+			Cigar: "3S5M1I2M", Rnext: "=", Pnext: 201, Tlen: 150,
+			Seq: "ACGTNACGTAC", Qual: "IIIIIIIIII#",
+			Tags: map[string]any{"NM": 1, "AS": -3, "XA": byte('x'),
+				"XZ": "hello", "XF": 0.5, "XB": "C,1,2,255",
+				"XH": []byte{0x1a, 0xe3}},
+		},
+		{
+			Qname: "read2", Flag: 4, Rname: "*", Pos: 0, Mapq: 0,
+			Cigar: "*", Rnext: "*", Pnext: 0, Tlen: 0,
+			Seq: "*", Qual: "*", Tags: map[string]any{},
+		},
+	}
+	input := toBAM(t, []string{"chr1", "chr2"}, want)
+	var got []*sam.SAM
+	for s, err := range IterReader(
+		NewBGZFReader(bytes.NewReader(toBGZF(t, input, 50)), 3)) {
+		if err != nil {
+			t.Fatalf("IterReader() failed: %v", err)
+		}
+		got = append(got, s)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("IterReader()=%v, want %v", got, want)
+	}
+}
+
+func TestIterReader_bad(t *testing.T) {
+	rec := []*sam.SAM{{Qname: "r", Rname: "*", Rnext: "*", Seq: "*",
+		Qual: "*", Cigar: "*"}}
+	good := toBAM(t, nil, rec)
+	inputs := map[string][]byte{
+		"bad magic": append([]byte("BAM\x02"), good[4:]...),
+		"truncated": good[:len(good)-3],
+		"bad ref":   toBAM(t, nil, []*sam.SAM{{Rname: "chr1"}}),
+	}
+	for name, input := range inputs {
+		var err error
+		for _, err = range IterReader(bytes.NewReader(input)) {
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			t.Errorf("IterReader(%s) succeeded, want error", name)
+		}
+	}
+}
+
+// Compresses data in BGZF format, with blocks of the given uncompressed size
+// and an empty last block.
+func toBGZF(t *testing.T, data []byte, size int) []byte {
+	buf := &bytes.Buffer{}
+	for {
+		n := min(size, len(data))
+		cdata := &bytes.Buffer{}
+		w, _ := flate.NewWriter(cdata, flate.DefaultCompression)
+		w.Write(data[:n])
+		if err := w.Close(); err != nil {
+			t.Fatalf("flate failed: %v", err)
+		}
+		header := []byte{31, 139, 8, 4, 0, 0, 0, 0, 0, 255, 6, 0,
+			'B', 'C', 2, 0, 0, 0}
+		binary.LittleEndian.PutUint16(header[16:],
+			uint16(len(header)+cdata.Len()+8-1))
+		buf.Write(header)
+		buf.Write(cdata.Bytes())
+		binary.Write(buf, binary.LittleEndian, crc32.ChecksumIEEE(data[:n]))
+		binary.Write(buf, binary.LittleEndian, uint32(n))
+		if n == 0 {
+			return buf.Bytes()
+		}
+		data = data[n:]
+	}
+}
+
+// Encodes records in (uncompressed) BAM format. Supports a subset of the
+// format, for testing.
+func toBAM(t *testing.T, refs []string, records []*sam.SAM) []byte {
+	le := binary.LittleEndian
+	refID := func(name string) int32 {
+		if name == "*" {
+			return -1
+		}
+		for i, ref := range refs {
+			if ref == name {
+				return int32(i)
+			}
+		}
+		return int32(len(refs)) // Invalid ID.
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(magic)
+	binary.Write(buf, le, int32(0)) // Header text.
+	binary.Write(buf, le, int32(len(refs)))
+	for _, ref := range refs {
+		binary.Write(buf, le, int32(len(ref)+1))
+		buf.WriteString(ref + "\x00")
+		binary.Write(buf, le, int32(1000))
+	}
+
+	for _, s := range records {
+		rec := &bytes.Buffer{}
+		rid := refID(s.Rname)
+		binary.Write(rec, le, rid)
+		binary.Write(rec, le, int32(s.Pos-1))
+		rec.WriteByte(byte(len(s.Qname) + 1))
+		rec.WriteByte(byte(s.Mapq))
+		binary.Write(rec, le, uint16(0)) // Bin.
+		var cigar []uint32
+		if s.Cigar != "*" {
+			n := 0
+			for _, c := range []byte(s.Cigar) {
+				if c >= '0' && c <= '9' {
+					n = n*10 + int(c-'0')
+					continue
+				}
+				op := bytes.IndexByte([]byte("MIDNSHP=X"), c)
+				cigar = append(cigar, uint32(n)<<4|uint32(op))
+				n = 0
+			}
+		}
+		binary.Write(rec, le, uint16(len(cigar)))
+		binary.Write(rec, le, uint16(s.Flag))
+		seq := s.Seq
+		if seq == "*" {
+			seq = ""
+		}
+		binary.Write(rec, le, int32(len(seq)))
+		nrid := rid
+		if s.Rnext != "=" {
+			nrid = refID(s.Rnext)
+		}
+		binary.Write(rec, le, nrid)
+		binary.Write(rec, le, int32(s.Pnext-1))
+		binary.Write(rec, le, int32(s.Tlen))
+		rec.WriteString(s.Qname + "\x00")
+		binary.Write(rec, le, cigar)
+		const bases = "=ACMGRSVTWYHKDBN"
+		for i := 0; i < len(seq); i += 2 {
+			b := byte(bytes.IndexByte([]byte(bases), seq[i])) << 4
+			if i+1 < len(seq) {
+				b |= byte(bytes.IndexByte([]byte(bases), seq[i+1]))
+			}
+			rec.WriteByte(b)
+		}
+		for i := range seq {
+			if s.Qual == "*" {
+				rec.WriteByte(0xff)
+			} else {
+				rec.WriteByte(s.Qual[i] - 33)
+			}
+		}
+		for _, name := range sortedTagNames(s.Tags) {
+			rec.WriteString(name)
+			switch v := s.Tags[name].(type) {
+			case int:
+				rec.WriteByte('i')
+				binary.Write(rec, le, int32(v))
+			case byte:
+				rec.WriteByte('A')
+				rec.WriteByte(v)
+			case string:
+				if name == "XB" { // Array of uint8.
+					rec.WriteByte('B')
+					rec.WriteByte('C')
+					binary.Write(rec, le, int32(3))
+					rec.Write([]byte{1, 2, 255})
+				} else {
+					rec.WriteByte('Z')
+					rec.WriteString(v + "\x00")
+				}
+			case float64:
+				rec.WriteByte('f')
+				binary.Write(rec, le, float32(v))
+			case []byte:
+				rec.WriteByte('H')
+				rec.WriteString("1AE3\x00")
+			default:
+				t.Fatalf("unsupported tag type: %T", v)
+			}
+		}
+		binary.Write(buf, le, int32(rec.Len()))
+		buf.Write(rec.Bytes())
+	}
+	return buf.Bytes()
+}
+
+// Returns the names of the given tags in a fixed order.
+func sortedTagNames(tags map[string]any) []string {
+	var names []string
+	for _, name := range []string{"NM", "AS", "XA", "XZ", "XF", "XB", "XH"} {
+		if _, ok := tags[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,188 @@
+// BGZF decompression.
+
+package bam
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/fluhus/kwas/util"
+)
+
+// Length of a BGZF block header, up to and including the BSIZE field.
+const bgzfHeaderLen = 18
+
+// Maximal uncompressed size of a BGZF block.
+const maxBlockSize = 1 << 16
+
+// IsBGZF returns whether b starts with a BGZF block header, whose first extra
+// subfield holds the block size, as written by samtools and htslib.
+// Needs at least 18 bytes to return true.
+func IsBGZF(b []byte) bool {
+	return len(b) >= bgzfHeaderLen &&
+		b[0] == 31 && b[1] == 139 && b[2] == 8 && b[3]&4 != 0 &&
+		binary.LittleEndian.Uint16(b[10:]) >= 6 &&
+		b[12] == 'B' && b[13] == 'C' &&
+		binary.LittleEndian.Uint16(b[14:]) == 2
+}
+
+// A BGZFReader decompresses a BGZF stream, decoding several blocks in
+// parallel.
+type BGZFReader struct {
+	blocks chan chan block // Decoded blocks, in stream order.
+	cur    []byte          // Remainder of the current block.
+	err    error           // Sticky error.
+	done   chan struct{}   // Closed by Close.
+	once   sync.Once
+}
+
+// A decoded block, or the error encountered while reading or decoding it.
+type block struct {
+	data []byte
+	err  error
+}
+
+// NewBGZFReader returns a reader that decompresses the BGZF stream in r,
+// using n goroutines for decoding blocks.
+// Close should be called to release its goroutines if r is not read to its
+// end.
+func NewBGZFReader(r io.Reader, n int) *BGZFReader {
+	if n < 1 {
+		panic(fmt.Sprintf("bad number of goroutines: %d", n))
+	}
+	br := &BGZFReader{
+		blocks: make(chan chan block, n*4),
+		done:   make(chan struct{}),
+	}
+	type job struct {
+		raw []byte
+		out chan block
+	}
+	jobs := make(chan job, n)
+
+	for range n {
+		go func() {
+			for j := range jobs {
+				data, err := inflateBlock(j.raw)
+				j.out <- block{data, err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(br.blocks)
+		defer close(jobs)
+		rr := bufio.NewReader(r)
+		for {
+			raw, err := readBlock(rr)
+			if err == io.EOF {
+				return
+			}
+			out := make(chan block, 1)
+			if err != nil {
+				out <- block{nil, err}
+			} else {
+				select {
+				case jobs <- job{raw, out}:
+				case <-br.done:
+					return
+				}
+			}
+			select {
+			case br.blocks <- out:
+			case <-br.done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return br
+}
+
+// Read reads decompressed data into p.
+func (r *BGZFReader) Read(p []byte) (int, error) {
+	for len(r.cur) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		out, ok := <-r.blocks
+		if !ok {
+			r.err = io.EOF
+			return 0, io.EOF
+		}
+		b := <-out
+		r.cur, r.err = b.data, b.err
+	}
+	n := copy(p, r.cur)
+	r.cur = r.cur[n:]
+	return n, nil
+}
+
+// Close stops the reader's goroutines. Does not close the underlying reader.
+func (r *BGZFReader) Close() error {
+	r.once.Do(func() {
+		close(r.done)
+		// Let pending decoders finish.
+		for out := range r.blocks {
+			<-out
+		}
+	})
+	return nil
+}
+
+// Reads a single raw BGZF block, including its header.
+func readBlock(r *bufio.Reader) ([]byte, error) {
+	header, err := r.Peek(bgzfHeaderLen)
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("bad BGZF block header: %w",
+			util.NotExpectingEOF(err))
+	}
+	if !IsBGZF(header) {
+		return nil, fmt.Errorf("bad BGZF block header: %v", header)
+	}
+	size := int(binary.LittleEndian.Uint16(header[16:])) + 1
+	if size < bgzfHeaderLen+8 {
+		return nil, fmt.Errorf("bad BGZF block size: %d", size)
+	}
+	raw := make([]byte, size)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, util.NotExpectingEOF(err)
+	}
+	return raw, nil
+}
+
+// Decompresses a raw BGZF block and checks its checksum.
+func inflateBlock(raw []byte) ([]byte, error) {
+	xlen := int(binary.LittleEndian.Uint16(raw[10:]))
+	start := 12 + xlen
+	end := len(raw) - 8
+	if start > end {
+		return nil, fmt.Errorf("bad BGZF extra field length: %d", xlen)
+	}
+	crc := binary.LittleEndian.Uint32(raw[end:])
+	isize := binary.LittleEndian.Uint32(raw[end+4:])
+	if isize > maxBlockSize {
+		return nil, fmt.Errorf("bad BGZF uncompressed block size: %d", isize)
+	}
+	data := make([]byte, isize)
+	fr := flate.NewReader(bytes.NewReader(raw[start:end]))
+	defer fr.Close()
+	if _, err := io.ReadFull(fr, data); err != nil {
+		return nil, fmt.Errorf("bad BGZF block data: %w", err)
+	}
+	if crc32.ChecksumIEEE(data) != crc {
+		return nil, fmt.Errorf("bad BGZF block checksum")
+	}
+	return data, nil
+}
@@ -0,0 +1,174 @@
+// Package bitmap provides integer sets that are stored either as sorted lists
+// or as bitmaps, whichever is smaller.
+//
+// Sets are meant for sample lists of HAS tuples, where rare kmers have short
+// lists and common kmers have dense bitmaps.
+package bitmap
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+)
+
+// Set is an immutable set of non-negative integers.
+// The zero value is an empty set.
+type Set struct {
+	sparse []int    // Sorted elements, if not dense.
+	dense  []uint64 // Bit i is on if i is in the set, if dense.
+	n      int      // Number of elements.
+}
+
+// FromSorted returns a set of the given elements, which should be sorted with
+// no duplicates. Picks the smaller representation.
+func FromSorted(a []int) Set {
+	if len(a) == 0 {
+		return Set{}
+	}
+	if a[0] < 0 {
+		panic(fmt.Sprintf("negative element: %d", a[0]))
+	}
+	for i := range a[1:] {
+		if a[i] >= a[i+1] {
+			panic(fmt.Sprintf("elements are not sorted or unique: %d, %d",
+				a[i], a[i+1]))
+		}
+	}
+	nwords := a[len(a)-1]/64 + 1
+	if nwords >= len(a) { // Sparse is smaller.
+		return Set{sparse: slices.Clone(a), n: len(a)}
+	}
+	words := make([]uint64, nwords)
+	for _, x := range a {
+		words[x/64] |= 1 << (x % 64)
+	}
+	return Set{dense: words, n: len(a)}
+}
+
+// FromWords returns a dense set from its bitmap, where bit i of word i/64 is
+// on if i is in the set. Takes ownership of the slice.
+func FromWords(words []uint64) Set {
+	n := 0
+	for _, w := range words {
+		n += bits.OnesCount64(w)
+	}
+	return Set{dense: trimWords(words), n: n}
+}
+
+// Len returns the number of elements in the set.
+func (s Set) Len() int {
+	return s.n
+}
+
+// IsDense returns whether the set is stored as a bitmap.
+func (s Set) IsDense() bool {
+	return s.dense != nil
+}
+
+// Words returns the bitmap of a dense set, or nil for a sparse set.
+// The returned slice should not be modified.
+func (s Set) Words() []uint64 {
+	return s.dense
+}
+
+// Contains returns whether x is in the set.
+func (s Set) Contains(x int) bool {
+	if s.dense != nil {
+		return x >= 0 && x/64 < len(s.dense) && s.dense[x/64]&(1<<(x%64)) != 0
+	}
+	_, ok := slices.BinarySearch(s.sparse, x)
+	return ok
+}
+
+// AppendInts appends the elements of the set to buf in ascending order, and
+// returns the result.
+func (s Set) AppendInts(buf []int) []int {
+	if s.dense == nil {
+		return append(buf, s.sparse...)
+	}
+	for i, w := range s.dense {
+		for w != 0 {
+			buf = append(buf, i*64+bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+	return buf
+}
+
+// IntersectionCount returns the number of elements in both sets.
+func (s Set) IntersectionCount(other Set) int {
+	switch {
+	case s.dense != nil && other.dense != nil:
+		n := 0
+		for i := range min(len(s.dense), len(other.dense)) {
+			n += bits.OnesCount64(s.dense[i] & other.dense[i])
+		}
+		return n
+	case s.dense != nil:
+		return other.IntersectionCount(s)
+	case other.dense != nil:
+		n := 0
+		for _, x := range s.sparse {
+			if other.Contains(x) {
+				n++
+			}
+		}
+		return n
+	default:
+		return sortedCommon(s.sparse, other.sparse)
+	}
+}
+
+// UnionCount returns the number of elements in either set.
+func (s Set) UnionCount(other Set) int {
+	return s.n + other.n - s.IntersectionCount(other)
+}
+
+// Union returns a set of the elements in either set.
+func (s Set) Union(other Set) Set {
+	if s.dense != nil && other.dense != nil {
+		a, b := s.dense, other.dense
+		if len(a) < len(b) {
+			a, b = b, a
+		}
+		words := slices.Clone(a)
+		for i, w := range b {
+			words[i] |= w
+		}
+		return FromWords(words)
+	}
+	a := s.AppendInts(nil)
+	a = append(a, other.AppendInts(nil)...)
+	slices.Sort(a)
+	return FromSorted(slices.Compact(a))
+}
+
+// Returns the number of common elements in two sorted lists.
+func sortedCommon(a, b []int) int {
+	n := 0
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			n++
+			i++
+			j++
+		}
+	}
+	return n
+}
+
+// Returns words without trailing zero words, or nil if all are zero.
+func trimWords(words []uint64) []uint64 {
+	for len(words) > 0 && words[len(words)-1] == 0 {
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	return words
+}
@@ -0,0 +1,102 @@
+package bitmap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/fluhus/gostuff/snm"
+)
+
+func TestFromSorted(t *testing.T) {
+	tests := []struct {
+		a     []int
+		dense bool
+	}{
+		{nil, false},
+		{[]int{5}, false},
+		{[]int{1, 1000}, false},
+		{[]int{0, 1, 2, 3}, true},
+		{[]int{3, 64, 100, 127, 128}, true},
+	}
+	for _, test := range tests {
+		s := FromSorted(test.a)
+		if s.IsDense() != test.dense {
+			t.Errorf("FromSorted(%v).IsDense()=%v, want %v",
+				test.a, s.IsDense(), test.dense)
+		}
+		if s.Len() != len(test.a) {
+			t.Errorf("FromSorted(%v).Len()=%v, want %v",
+				test.a, s.Len(), len(test.a))
+		}
+		if got := s.AppendInts(nil); !slices.Equal(got, test.a) {
+			t.Errorf("FromSorted(%v).AppendInts()=%v, want %v",
+				test.a, got, test.a)
+		}
+		for _, x := range test.a {
+			if !s.Contains(x) {
+				t.Errorf("FromSorted(%v).Contains(%v)=false, want true",
+					test.a, x)
+			}
+		}
+		if s.Contains(2000) {
+			t.Errorf("FromSorted(%v).Contains(2000)=true, want false", test.a)
+		}
+	}
+}
+
+func TestFromSorted_bad(t *testing.T) {
+	for _, a := range [][]int{{2, 1}, {1, 1}, {-1, 2}} {
+		func() {
+			defer func() { recover() }()
+			FromSorted(a)
+			t.Errorf("FromSorted(%v) succeeded, want panic", a)
+		}()
+	}
+}
+
+func TestSet_counts(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for _, n := range []int{1, 10, 100, 1000} {
+		for _, m := range []int{5, 100, 2000} {
+			a := snm.Sorted(rnd.Perm(m)[:min(n, m)])
+			b := snm.Sorted(rnd.Perm(m * 2)[:min(n, m)])
+			sa, sb := FromSorted(a), FromSorted(b)
+
+			wantInter := 0
+			for _, x := range a {
+				if _, ok := slices.BinarySearch(b, x); ok {
+					wantInter++
+				}
+			}
+			wantUnion := len(a) + len(b) - wantInter
+			if got := sa.IntersectionCount(sb); got != wantInter {
+				t.Errorf("IntersectionCount(%v,%v)=%v, want %v",
+					a, b, got, wantInter)
+			}
+			if got := sb.IntersectionCount(sa); got != wantInter {
+				t.Errorf("IntersectionCount(%v,%v)=%v, want %v",
+					b, a, got, wantInter)
+			}
+			if got := sa.UnionCount(sb); got != wantUnion {
+				t.Errorf("UnionCount(%v,%v)=%v, want %v",
+					a, b, got, wantUnion)
+			}
+
+			union := slices.Compact(snm.Sorted(append(slices.Clone(a), b...)))
+			if got := sa.Union(sb).AppendInts(nil); !slices.Equal(got, union) {
+				t.Errorf("Union(%v,%v)=%v, want %v", a, b, got, union)
+			}
+		}
+	}
+}
+
+func BenchmarkIntersectionCount(b *testing.B) {
+	const n = 1000
+	x := FromSorted(snm.Sorted(rand.Perm(n * 2)[:n]))
+	y := FromSorted(snm.Sorted(rand.Perm(n * 2)[:n]))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.IntersectionCount(y)
+	}
+}
@@ -1,4 +1,4 @@
-"""Analyzes the results of smfqhg.py. Looks at species distribution."""
+"""Analyzes the results of smfqhg. Looks at species distribution."""
 import gzip
 import json
 import math
@@ -14,6 +14,14 @@ PATHWAY_FILE = f'{DIR}/genes_pathway.json.gz'
 KO_FILE = f'{DIR}/genes_ko.json.gz'
 KO_DEFS_FILE = f'{DIR}/ko_def.json.gz'
 KO_CAT_FILE = f'{DIR}/ko_cat.json.gz'
+ALPHA = 0.05  # Significance threshold for smfqhg q-values.
+
+
+def load_tests(file):
+    """Returns the significant and the tested reference names in an smfqhg
+    TSV."""
+    d = pd.read_csv(file, sep='\t', keep_default_na=False)
+    return set(d['rname'][d['q'] <= ALPHA]), set(d['rname'])
 
 
 def cut_suffix(a, prefix):
@@ -48,10 +56,7 @@ def pie_params(a):
 def by_species():
     print('by_species2')
     tax = pd.read_csv('taxonomy.csv.gz')
-    d = json.load(gzip.open('fishers.bwt.json.gz'))
-    sig_species = set(d['sig'])
-    found_species = set(d['found'])
-    del d
+    sig_species, found_species = load_tests('fishers.bwt.tsv')
 
     print(tax.columns)
 
@@ -80,13 +85,10 @@ def by_species():
 
 def by_kegg():
     print('Loading tests')
-    d = json.load(gzip.open('fishers.dmnd.json.gz'))
-    enrch = frozenset(d['sig'])
-    found = frozenset(d['found'])
+    enrch, found = map(frozenset, load_tests('fishers.dmnd.tsv'))
     nfound, nenrch = len(found), len(enrch)
     print('Found', nfound, 'enriched', nenrch)
     assert len(enrch - found) == 0, f'{enrch - found}'
-    del d
 
     print('Loading KO')
     ko = json.load(gzip.open(KO_FILE))
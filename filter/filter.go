@@ -4,12 +4,11 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io"
 	"os"
 
 	"github.com/fluhus/gostuff/aio"
 	"github.com/fluhus/gostuff/ptimer"
-	"github.com/fluhus/kwas/kmr"
+	"github.com/fluhus/kwas/kmr/v2"
 	"github.com/fluhus/kwas/util"
 )
 
@@ -23,20 +22,18 @@ var (
 func main() {
 	fmt.Println("Opening files")
 	flag.Parse()
-	fin, err := aio.Open(*inFile)
-	util.Die(err)
 	fout, err := aio.Create(*outFile)
 	util.Die(err)
 	kw := kmr.NewWriter(fout)
 
 	fmt.Println("Filtering")
-	cnt := &kmr.CountTuple{}
 	kept := 0
 	var last kmr.Kmer
 	pt := ptimer.NewFunc(func(i int) string {
 		return fmt.Sprintf("read %d, wrote %d (%d%%)", i, kept, kept*100/i)
 	})
-	for err = cnt.Decode(fin); err == nil; err = cnt.Decode(fin) {
+	for cnt, err := range kmr.IterTuplesFile[kmr.CountHandler](*inFile) {
+		util.Die(err)
 		pt.Inc()
 		if cnt.Kmer.Less(last) {
 			util.Die(fmt.Errorf("kmers not in order: %v %v", last, cnt.Kmer))
@@ -46,16 +43,9 @@ func main() {
 			continue
 		}
 		kept++
-		err = kw.Write(last)
-		if err != nil {
-			break
-		}
-	}
-	if err != io.EOF {
-		util.Die(err)
+		util.Die(kw.Write(last))
 	}
-	fin.Close()
-	fout.Close()
+	util.Die(fout.Close())
 	pt.Done()
 
 	if *del {
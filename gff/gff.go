@@ -0,0 +1,172 @@
+// Package gff parses GFF3 annotation files and finds the features that
+// overlap reference positions.
+package gff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fluhus/biostuff/regions"
+	"github.com/fluhus/gostuff/aio"
+)
+
+// Feature is a single annotated feature.
+type Feature struct {
+	SeqID  string
+	Type   string
+	Start  int  // 0-based, inclusive.
+	End    int  // 0-based, exclusive.
+	Strand byte // '+', '-', or '.' if unknown.
+	Attrs  map[string]string
+}
+
+// Name returns the feature's Name attribute, or its ID if it has no name.
+func (f *Feature) Name() string {
+	if name := f.Attrs["Name"]; name != "" {
+		return name
+	}
+	return f.Attrs["ID"]
+}
+
+// IterFile iterates over the features in a GFF file.
+func IterFile(file string) iter.Seq2[*Feature, error] {
+	return func(yield func(*Feature, error) bool) {
+		f, err := aio.Open(file)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer f.Close()
+		for ft, err := range IterReader(f) {
+			if !yield(ft, err) {
+				return
+			}
+		}
+	}
+}
+
+// IterReader iterates over the features in a GFF stream.
+// Comments are skipped, and reading stops at an embedded FASTA section.
+func IterReader(r io.Reader) iter.Seq2[*Feature, error] {
+	return func(yield func(*Feature, error) bool) {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, 1<<24)
+		i := 0
+		for sc.Scan() {
+			i++
+			line := sc.Text()
+			if line == "##FASTA" {
+				return
+			}
+			if line == "" || line[0] == '#' {
+				continue
+			}
+			f, err := parseLine(line)
+			if err != nil {
+				yield(nil, fmt.Errorf("line %d: %w", i, err))
+				return
+			}
+			if !yield(f, nil) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Parses a single feature line.
+func parseLine(line string) (*Feature, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 9 {
+		return nil, fmt.Errorf("bad number of fields: %d, want 9",
+			len(fields))
+	}
+	start, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("bad start: %w", err)
+	}
+	end, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("bad end: %w", err)
+	}
+	if start < 1 || end < start {
+		return nil, fmt.Errorf("bad range: %d-%d", start, end)
+	}
+	if len(fields[6]) != 1 {
+		return nil, fmt.Errorf("bad strand: %q", fields[6])
+	}
+	attrs := map[string]string{}
+	for _, attr := range strings.Split(fields[8], ";") {
+		if attr == "" || attr == "." {
+			continue
+		}
+		k, v, ok := strings.Cut(attr, "=")
+		if !ok {
+			return nil, fmt.Errorf("bad attribute: %q", attr)
+		}
+		if v, err = url.PathUnescape(v); err != nil {
+			return nil, fmt.Errorf("bad attribute: %q: %w", attr, err)
+		}
+		attrs[k] = v
+	}
+	return &Feature{
+		SeqID:  fields[0],
+		Type:   fields[2],
+		Start:  start - 1,
+		End:    end,
+		Strand: fields[6][0],
+		Attrs:  attrs,
+	}, nil
+}
+
+// Index finds the features that overlap given ranges.
+type Index struct {
+	features map[string][]*Feature     // By sequence ID.
+	idx      map[string]*regions.Index // By sequence ID.
+	n        int                       // Length of queried ranges.
+}
+
+// NewIndex returns an index for querying ranges of length n on the given
+// features.
+func NewIndex(features []*Feature, n int) *Index {
+	if n < 1 {
+		panic(fmt.Sprintf("bad range length: %d", n))
+	}
+	byID := map[string][]*Feature{}
+	for _, f := range features {
+		byID[f.SeqID] = append(byID[f.SeqID], f)
+	}
+	idx := map[string]*regions.Index{}
+	for id, fs := range byID {
+		starts := make([]int, len(fs))
+		ends := make([]int, len(fs))
+		for i, f := range fs {
+			// A range that starts here or later overlaps the feature.
+			starts[i] = f.Start - n + 1
+			ends[i] = f.End
+		}
+		idx[id] = regions.NewIndex(starts, ends)
+	}
+	return &Index{byID, idx, n}
+}
+
+// Overlapping returns the features on the given sequence that overlap the
+// range from pos (0-based) to pos+n.
+func (idx *Index) Overlapping(seqID string, pos int) []*Feature {
+	r := idx.idx[seqID]
+	if r == nil {
+		return nil
+	}
+	var result []*Feature
+	for _, i := range r.At(pos) {
+		result = append(result, idx.features[seqID][i])
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package gff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIterReader(t *testing.T) {
+	input := "##gff-version 3\n" +
+		"chr1\tsrc\tgene\t1\t10\t.\t+\t.\tID=g1;Name=abc%3B1\n" +
+		"# Comment\n" +
+		"\n" +
+		"chr2\tsrc\tCDS\t5\t5\t.\t-\t0\t.\n" +
+		"##FASTA\n" +
+		">chr1\n" +
+		"ACGT\n"
+	want := []*Feature{
+		{"chr1", "gene", 0, 10, '+', map[string]string{
+			"ID": "g1", "Name": "abc;1"}},
+		{"chr2", "CDS", 4, 5, '-', map[string]string{}},
+	}
+	var got []*Feature
+	for f, err := range IterReader(strings.NewReader(input)) {
+		if err != nil {
+			t.Fatalf("IterReader(%q) failed: %v", input, err)
+		}
+		got = append(got, f)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("IterReader(%q)=%v, want %v", input, got, want)
+	}
+	if got[0].Name() != "abc;1" || got[1].Name() != "" {
+		t.Fatalf("Name()=%q,%q, want %q,%q",
+			got[0].Name(), got[1].Name(), "abc;1", "")
+	}
+}
+
+func TestIterReader_bad(t *testing.T) {
+	inputs := []string{
+		"chr1\tsrc\tgene\t1\t10\t.\t+\t.\n",
+		"chr1\tsrc\tgene\ta\t10\t.\t+\t.\t.\n",
+		"chr1\tsrc\tgene\t10\t1\t.\t+\t.\t.\n",
+		"chr1\tsrc\tgene\t0\t1\t.\t+\t.\t.\n",
+		"chr1\tsrc\tgene\t1\t10\t.\t+-\t.\t.\n",
+		"chr1\tsrc\tgene\t1\t10\t.\t+\t.\tID\n",
+	}
+	for _, input := range inputs {
+		for _, err := range IterReader(strings.NewReader(input)) {
+			if err == nil {
+				t.Errorf("IterReader(%q) succeeded, want error", input)
+			}
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	fs := []*Feature{
+		{SeqID: "a", Start: 10, End: 20},
+		{SeqID: "a", Start: 15, End: 30},
+		{SeqID: "b", Start: 0, End: 5},
+	}
+	idx := NewIndex(fs, 3)
+	tests := []struct {
+		seqID string
+		pos   int
+		want  []*Feature
+	}{
+		{"a", 7, nil},
+		{"a", 8, fs[:1]},
+		{"a", 13, fs[:2]},
+		{"a", 19, fs[:2]},
+		{"a", 20, fs[1:2]},
+		{"a", 30, nil},
+		{"b", 0, fs[2:]},
+		{"c", 0, nil},
+	}
+	for _, test := range tests {
+		got := idx.Overlapping(test.seqID, test.pos)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Overlapping(%q,%d)=%v, want %v",
+				test.seqID, test.pos, got, test.want)
+		}
+	}
+}
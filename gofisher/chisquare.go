@@ -0,0 +1,61 @@
+// Chi-square and G tests.
+
+package gofisher
+
+import (
+	"math"
+)
+
+// ChiSquare returns the odds ratio and the p-value of Pearson's chi-square
+// test of independence. If correct is true, applies Yates's continuity
+// correction, like R's chisq.test.
+func ChiSquare(a, b, c, d int, correct bool) (float64, float64) {
+	checkInput(a, b, c, d)
+	obs, exp := observedExpected(a, b, c, d)
+	yates := 0.0
+	if correct {
+		yates = min(0.5, math.Abs(obs[0]-exp[0]))
+	}
+	stat := 0.0
+	for i := range obs {
+		x := math.Abs(obs[i]-exp[i]) - yates
+		stat += x * x / exp[i]
+	}
+	return odr(a, b, c, d), chiSquare1SF(stat)
+}
+
+// GTest returns the odds ratio and the p-value of the G-test
+// (log-likelihood ratio test) of independence.
+func GTest(a, b, c, d int) (float64, float64) {
+	checkInput(a, b, c, d)
+	obs, exp := observedExpected(a, b, c, d)
+	stat := 0.0
+	for i := range obs {
+		if obs[i] > 0 {
+			stat += 2 * obs[i] * math.Log(obs[i]/exp[i])
+		}
+	}
+	return odr(a, b, c, d), chiSquare1SF(stat)
+}
+
+// Returns the observed counts of a table and their expected values under
+// independence. Expected values are NaN if a margin is 0.
+func observedExpected(a, b, c, d int) ([4]float64, [4]float64) {
+	n := float64(a + b + c + d)
+	r1, r2 := float64(a+b), float64(c+d)
+	c1, c2 := float64(a+c), float64(b+d)
+	obs := [4]float64{float64(a), float64(b), float64(c), float64(d)}
+	exp := [4]float64{r1 * c1 / n, r1 * c2 / n, r2 * c1 / n, r2 * c2 / n}
+	for i := range exp {
+		if exp[i] == 0 {
+			exp[i] = math.NaN()
+		}
+	}
+	return obs, exp
+}
+
+// Returns the probability that a chi-square variable with 1 degree of
+// freedom is at least x.
+func chiSquare1SF(x float64) float64 {
+	return math.Erfc(math.Sqrt(x / 2))
+}
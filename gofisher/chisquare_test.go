@@ -0,0 +1,52 @@
+package gofisher
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChiSquare(t *testing.T) {
+	tests := []struct {
+		input   [4]int
+		correct bool
+		wantODR float64
+		wantP   float64
+	}{
+		{[4]int{3, 1, 1, 3}, true, 9, 0.4795001221869535}, // R: TeaTasting.
+		{[4]int{3, 1, 1, 3}, false, 9, 0.15729920705028513},
+		{[4]int{60, 10, 30, 25}, true, 5, 0.00026029111164008976},
+		{[4]int{60, 10, 30, 25}, false, 5, 0.0001168842401061317},
+		{[4]int{0, 10, 12, 2}, true, 0, 0.00019426949889741752},
+		{[4]int{0, 10, 12, 2}, false, 0, 3.467107687269651e-05},
+		{[4]int{0, 0, 12, 2}, false, math.NaN(), math.NaN()},
+	}
+	for _, test := range tests {
+		in := test.input
+		odr, p := ChiSquare(in[0], in[1], in[2], in[3], test.correct)
+		if !equalFloat64(odr, test.wantODR) || !equalFloat64(p, test.wantP) {
+			t.Errorf("ChiSquare(%v,%v)=%f,%f want %f,%f",
+				in, test.correct, odr, p, test.wantODR, test.wantP)
+		}
+	}
+}
+
+func TestGTest(t *testing.T) {
+	tests := []struct {
+		input   [4]int
+		wantODR float64
+		wantP   float64
+	}{
+		{[4]int{3, 1, 1, 3}, 9, 0.14797595938502728},
+		{[4]int{60, 10, 30, 25}, 5, 0.0001057579858486357},
+		{[4]int{12, 5, 7, 7}, 2.4, 0.24086404807478545},
+		{[4]int{0, 10, 12, 2}, 0, 3.0452917278384293e-06},
+	}
+	for _, test := range tests {
+		in := test.input
+		odr, p := GTest(in[0], in[1], in[2], in[3])
+		if !equalFloat64(odr, test.wantODR) || !equalFloat64(p, test.wantP) {
+			t.Errorf("GTest(%v)=%f,%f want %f,%f",
+				in, odr, p, test.wantODR, test.wantP)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Cochran-Mantel-Haenszel test.
+
+package gofisher
+
+import (
+	"math"
+)
+
+// CMH returns the Mantel-Haenszel common odds ratio and the p-value of the
+// Cochran-Mantel-Haenszel test of conditional independence, over strata of
+// 2x2 tables, given as {a, b, c, d}. If correct is true, applies a
+// continuity correction, like R's mantelhaen.test.
+// Strata with fewer than 2 samples carry no information and are skipped.
+// If no stratum is informative, that is every stratum is skipped or has a
+// zero row or column sum, returns NaN for both values.
+func CMH(tables [][4]int, correct bool) (float64, float64) {
+	delta, vr := 0.0, 0.0
+	num, den := 0.0, 0.0
+	for _, t := range tables {
+		a, b, c, d := t[0], t[1], t[2], t[3]
+		checkInput(a, b, c, d)
+		n := float64(a + b + c + d)
+		if n < 2 {
+			continue
+		}
+		r1, r2 := float64(a+b), float64(c+d)
+		c1, c2 := float64(a+c), float64(b+d)
+		delta += float64(a) - r1*c1/n
+		vr += r1 * r2 * c1 * c2 / (n * n * (n - 1))
+		num += float64(a) * float64(d) / n
+		den += float64(b) * float64(c) / n
+	}
+	yates := 0.0
+	if correct && math.Abs(delta) >= 0.5 {
+		yates = 0.5
+	}
+	x := math.Abs(delta) - yates
+	stat := x * x / vr
+	return num / den, chiSquare1SF(stat)
+}
@@ -0,0 +1,39 @@
+package gofisher
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCMH(t *testing.T) {
+	// R: mantelhaen.test(UCBAdmissions).
+	ucb := [][4]int{
+		{512, 89, 313, 19},
+		{353, 17, 207, 8},
+		{120, 202, 205, 391},
+		{138, 131, 279, 244},
+		{53, 94, 138, 299},
+		{22, 24, 351, 317},
+	}
+	tests := []struct {
+		input   [][4]int
+		correct bool
+		wantODR float64
+		wantP   float64
+	}{
+		{ucb, true, 0.9046968282586231, 0.23226346281704818},
+		{ucb, false, 0.9046968282586231, 0.21692369705551828},
+		{append(ucb, [4]int{1, 0, 0, 0}), true,
+			0.9046968282586231, 0.23226346281704818},
+		{nil, true, math.NaN(), math.NaN()},
+		{[][4]int{{1, 0, 0, 0}, {0, 0, 3, 4}, {2, 0, 5, 0}}, false,
+			math.NaN(), math.NaN()},
+	}
+	for _, test := range tests {
+		odr, p := CMH(test.input, test.correct)
+		if !equalFloat64(odr, test.wantODR) || !equalFloat64(p, test.wantP) {
+			t.Errorf("CMH(%v,%v)=%f,%f want %f,%f",
+				test.input, test.correct, odr, p, test.wantODR, test.wantP)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Multiple testing corrections.
+
+package gofisher
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Correction returns the multiple testing correction of the given name:
+// bonferroni or bh.
+func Correction(name string) (func([]float64) []float64, error) {
+	switch name {
+	case "bonferroni":
+		return Bonferroni, nil
+	case "bh":
+		return BH, nil
+	default:
+		return nil, fmt.Errorf("bad correction: %q, want bonferroni or bh",
+			name)
+	}
+}
+
+// Bonferroni returns p-values adjusted with Bonferroni's correction, capped
+// at 1.
+func Bonferroni(p []float64) []float64 {
+	result := make([]float64, len(p))
+	for i, x := range p {
+		result[i] = min(x*float64(len(p)), 1)
+	}
+	return result
+}
+
+// BH returns q-values, which are p-values adjusted with the
+// Benjamini-Hochberg procedure for controlling the false discovery rate.
+// Same as R's p.adjust with method "BH".
+func BH(p []float64) []float64 {
+	idx := make([]int, len(p))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(a, b int) int {
+		return cmp.Compare(p[a], p[b])
+	})
+	result := make([]float64, len(p))
+	q := 1.0
+	for i := len(idx) - 1; i >= 0; i-- {
+		q = min(q, p[idx[i]]*float64(len(p))/float64(i+1))
+		result[idx[i]] = q
+	}
+	return result
+}
@@ -0,0 +1,51 @@
+package gofisher
+
+import (
+	"testing"
+)
+
+func TestBonferroni(t *testing.T) {
+	input := []float64{0.01, 0.2, 0.001, 0.5}
+	want := []float64{0.04, 0.8, 0.004, 1}
+	got := Bonferroni(input)
+	for i := range want {
+		if !equalFloat64(got[i], want[i]) {
+			t.Fatalf("Bonferroni(%v)=%v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestBH(t *testing.T) {
+	// Values from R: p.adjust(input, "BH").
+	input := []float64{0.01, 0.04, 0.03, 0.005, 0.5, 0.04}
+	want := []float64{0.03, 0.048, 0.048, 0.03, 0.5, 0.048}
+	got := BH(input)
+	for i := range want {
+		if !equalFloat64(got[i], want[i]) {
+			t.Fatalf("BH(%v)=%v, want %v", input, got, want)
+		}
+	}
+	if got := BH(nil); len(got) != 0 {
+		t.Fatalf("BH(nil)=%v, want []", got)
+	}
+}
+
+func TestCorrection(t *testing.T) {
+	input := []float64{0.01, 0.04, 0.03}
+	for name, want := range map[string]func([]float64) []float64{
+		"bonferroni": Bonferroni, "bh": BH} {
+		f, err := Correction(name)
+		if err != nil {
+			t.Fatalf("Correction(%q) failed: %v", name, err)
+		}
+		got, wnt := f(input), want(input)
+		for i := range wnt {
+			if got[i] != wnt[i] {
+				t.Fatalf("Correction(%q)(%v)=%v, want %v", name, input, got, wnt)
+			}
+		}
+	}
+	if _, err := Correction("BH"); err == nil {
+		t.Fatalf("Correction(%q) succeeded, want error", "BH")
+	}
+}
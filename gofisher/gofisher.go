@@ -1,30 +1,48 @@
-// Package gofisher provides an implementation of Fisher's exact test.
+// Package gofisher provides an implementation of Fisher's exact test,
+// along with other tests of contingency tables: chi-square, G,
+// Cochran-Mantel-Haenszel and Cochran-Armitage.
+//
+// All functions are safe for concurrent use.
 package gofisher
 
 import (
 	"fmt"
 	"math"
-
-	"golang.org/x/exp/slices"
+	"sort"
+	"sync"
+	"sync/atomic"
 )
 
-// Log-factorial cache.
-var facs = []float64{0}
+// Log-factorial cache. Readers load a snapshot without locking; writers
+// replace it with a longer copy under facsMu.
+var (
+	facs   atomic.Pointer[[]float64]
+	facsMu sync.Mutex
+)
 
-// Fill the log-factorial cache up to i.
-func fillFacs(i int) {
-	if i > len(facs) {
-		facs = slices.Grow(facs, i-len(facs))
+// Returns a log-factorial table that goes up to at least n.
+func logFacs(n int) []float64 {
+	if f := facs.Load(); f != nil && len(*f) > n {
+		return *f
 	}
-	for j := len(facs); j <= i; j++ {
-		facs = append(facs, facs[j-1]+math.Log(float64(j)))
+	facsMu.Lock()
+	defer facsMu.Unlock()
+	old := []float64{0}
+	if f := facs.Load(); f != nil {
+		if len(*f) > n {
+			return *f
+		}
+		old = *f
 	}
-}
-
-// Returns the probability of a single contingency table.
-func fisherSingleTable(a, b, c, d, n int) float64 {
-	return math.Exp(facs[a+b] + facs[c+d] + facs[a+c] + facs[b+d] - facs[n] -
-		facs[a] - facs[b] - facs[c] - facs[d])
+	// Grow geometrically, so that copying is amortized.
+	n = max(n, 2*len(old))
+	f := make([]float64, len(old), n+1)
+	copy(f, old)
+	for j := len(f); j <= n; j++ {
+		f = append(f, f[j-1]+math.Log(float64(j)))
+	}
+	facs.Store(&f)
+	return f
 }
 
 // Panics if one of the inputs is negative.
@@ -56,91 +74,199 @@ func odr(a, b, c, d int) float64 {
 	}
 }
 
-// Greater returns the probability that a is at least as big.
-func Greater(a, b, c, d int) (float64, float64) {
-	checkInput(a, b, c, d)
+// Relative tolerance for tables as likely as the input, like in R.
+var logRelErr = math.Log1p(1e-7)
+
+// Terms smaller than the sum by this log-factor are negligible.
+const negligible = 40
+
+// The hypergeometric distribution of a, given the margins of a table.
+type hyper struct {
+	r1, c1, n int     // a+b, a+c and the total.
+	lo, hi    int     // Range of a.
+	lconst    float64 // Log-probability terms that do not depend on a.
+	facs      []float64
+}
+
+// Returns the distribution of a, given the margins of the input table.
+func newHyper(a, b, c, d int) *hyper {
 	n := a + b + c + d
-	or := odr(a, b, c, d)
-	fillFacs(n)
-	p := 0.0
-	for b >= 0 && c >= 0 {
-		p += fisherSingleTable(a, b, c, d, n)
-		a++
-		b--
-		c--
-		d++
+	f := logFacs(n)
+	return &hyper{
+		r1: a + b, c1: a + c, n: n,
+		lo:     max(0, a-d),
+		hi:     a + min(b, c),
+		lconst: f[a+b] + f[c+d] + f[a+c] + f[b+d] - f[n],
+		facs:   f,
 	}
-	return or, p
 }
 
-// Less returns the probability that a is at most as big.
-func Less(a, b, c, d int) (float64, float64) {
-	checkInput(a, b, c, d)
-	n := a + b + c + d
-	or := odr(a, b, c, d)
-	fillFacs(n)
-	p := 0.0
-	for a >= 0 && d >= 0 {
-		p += fisherSingleTable(a, b, c, d, n)
-		a--
-		b++
-		c++
-		d--
+// Returns the log-probability of a=x.
+func (h *hyper) logp(x int) float64 {
+	f := h.facs
+	return h.lconst - f[x] - f[h.r1-x] - f[h.c1-x] - f[h.n-h.r1-h.c1+x]
+}
+
+// Returns the most likely value of a.
+func (h *hyper) mode() int {
+	m := int((float64(h.r1) + 1) * (float64(h.c1) + 1) / (float64(h.n) + 2))
+	return min(max(m, h.lo), h.hi)
+}
+
+// Returns the log-probability that a is from x on, in the direction of step.
+// Stops when the terms become negligible.
+func (h *hyper) tail(x, step int) float64 {
+	sum := math.Inf(-1)
+	prev := math.Inf(1)
+	for ; x >= h.lo && x <= h.hi; x += step {
+		t := h.logp(x)
+		if t < prev && t < sum-negligible {
+			break
+		}
+		sum = logAdd(sum, t)
+		prev = t
 	}
-	return or, p
+	return sum
 }
 
-// TwoSided returns the probability of a's at most as likely as a.
-func TwoSided(a, b, c, d int) (float64, float64) {
+// Returns log(exp(a)+exp(b)).
+func logAdd(a, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+	if math.IsInf(b, -1) {
+		return a
+	}
+	return a + math.Log1p(math.Exp(b-a))
+}
+
+// Alternative is the alternative hypothesis of a test.
+type Alternative int
+
+const (
+	AltGreater  Alternative = iota // a is greater than expected.
+	AltLess                        // a is less than expected.
+	AltTwoSided                    // a is unlikely, in either direction.
+)
+
+// ParseAlternative returns the alternative hypothesis of the given name:
+// greater, less or two (two-sided).
+func ParseAlternative(name string) (Alternative, error) {
+	switch name {
+	case "greater":
+		return AltGreater, nil
+	case "less":
+		return AltLess, nil
+	case "two":
+		return AltTwoSided, nil
+	default:
+		return 0, fmt.Errorf("bad alternative: %q, want greater, less or two",
+			name)
+	}
+}
+
+// Result is the result of a single test.
+type Result struct {
+	OddsRatio float64 // Sample odds ratio, ad/bc.
+	P         float64 // P-value.
+	LogP      float64 // Natural log of P, accurate also when P underflows.
+
+	// Mid-p value: P minus half the probability of the tables that are as
+	// likely as the input.
+	MidP float64
+}
+
+// Test runs Fisher's exact test on the table [[a, b], [c, d]].
+// Two-sided p-values sum the tables that are at most as likely as the input,
+// like R's fisher.test.
+func Test(a, b, c, d int, alt Alternative) Result {
 	checkInput(a, b, c, d)
-	n := a + b + c + d
-	or := odr(a, b, c, d)
-	fillFacs(n)
-	aOriginal := a
-	pOriginal := fisherSingleTable(a, b, c, d, n)
-
-	p := pOriginal
-
-	// First side, make a low.
-	diff := min(a, d)
-	a -= diff
-	b += diff
-	c += diff
-	d -= diff
-	for a < aOriginal {
-		pCurrent := fisherSingleTable(a, b, c, d, n)
-		if pCurrent > pOriginal { // Event is more probable than input.
-			break
+	h := newHyper(a, b, c, d)
+	lp := h.logp(a)
+	var logP, logTies float64
+	switch alt {
+	case AltGreater:
+		logP, logTies = h.tail(a, 1), lp
+	case AltLess:
+		logP, logTies = h.tail(a, -1), lp
+	case AltTwoSided:
+		logP, logTies = h.twoSided(a)
+	default:
+		panic(fmt.Sprintf("bad alternative: %d", alt))
+	}
+	logP = min(logP, 0)
+	p := math.Exp(logP)
+	return Result{
+		OddsRatio: odr(a, b, c, d),
+		P:         p,
+		LogP:      logP,
+		MidP:      max(p-math.Exp(logTies)/2, 0),
+	}
+}
+
+// Returns the log-probability of the values that are at most as likely as x,
+// and the log-probability of those that are as likely as x.
+func (h *hyper) twoSided(x int) (float64, float64) {
+	lpx := h.logp(x)
+	thr := lpx + logRelErr
+	m := h.mode()
+	ties := lpx
+
+	// The side of x, away from the mode.
+	step := 1
+	if x <= m {
+		step = -1
+	}
+	sum := h.tail(x, step)
+
+	// The other side: the nearest value to the mode that is at most as likely
+	// as x. Probabilities are monotone on each side of the mode.
+	var y int
+	if step == -1 {
+		from := max(x+1, m)
+		y = from + sort.Search(h.hi-from+1, func(i int) bool {
+			return h.logp(from+i) <= thr
+		})
+		if y > h.hi {
+			return sum, ties
 		}
-		p += pCurrent
-		a++
-		b--
-		c--
-		d++
-	}
-
-	// Second side, make a high.
-	diff = min(b, c)
-	a += diff
-	b -= diff
-	c -= diff
-	d += diff
-	for a > aOriginal {
-		pCurrent := fisherSingleTable(a, b, c, d, n)
-		if pCurrent > pOriginal { // Event is more probable than input.
-			break
+	} else {
+		to := min(x-1, m)
+		y = to - sort.Search(to-h.lo+1, func(i int) bool {
+			return h.logp(to-i) <= thr
+		})
+		if y < h.lo {
+			return sum, ties
 		}
-		p += pCurrent
-		a--
-		b++
-		c++
-		d--
 	}
+	if lpy := h.logp(y); lpy >= lpx-logRelErr {
+		ties = logAdd(ties, lpy)
+	}
+	return logAdd(sum, h.tail(y, -step)), ties
+}
 
-	return or, p
+// Greater returns the probability that a is at least as big.
+func Greater(a, b, c, d int) (float64, float64) {
+	r := Test(a, b, c, d, AltGreater)
+	return r.OddsRatio, r.P
+}
+
+// Less returns the probability that a is at most as big.
+func Less(a, b, c, d int) (float64, float64) {
+	r := Test(a, b, c, d, AltLess)
+	return r.OddsRatio, r.P
+}
+
+// TwoSided returns the probability of a's at most as likely as a.
+func TwoSided(a, b, c, d int) (float64, float64) {
+	r := Test(a, b, c, d, AltTwoSided)
+	return r.OddsRatio, r.P
 }
 
-// Clear clears the log-factorial cache.
+// Clear clears the log-factorial cache, to free its memory. Calling it is
+// optional; the cache is safe to keep between and during tests.
 func Clear() {
-	facs = []float64{0}
+	facsMu.Lock()
+	facs.Store(nil)
+	facsMu.Unlock()
 }
@@ -2,6 +2,7 @@ package gofisher
 
 import (
 	"math"
+	"sync"
 	"testing"
 
 	"github.com/fluhus/gostuff/gnum"
@@ -106,6 +107,129 @@ func TestBadInput(t *testing.T) {
 	}
 }
 
+func TestParseAlternative(t *testing.T) {
+	tests := []struct {
+		name string
+		want Alternative
+	}{
+		{"greater", AltGreater}, {"less", AltLess}, {"two", AltTwoSided},
+	}
+	for _, test := range tests {
+		got, err := ParseAlternative(test.name)
+		if err != nil {
+			t.Fatalf("ParseAlternative(%q) failed: %v", test.name, err)
+		}
+		if got != test.want {
+			t.Fatalf("ParseAlternative(%q)=%v, want %v", test.name, got,
+				test.want)
+		}
+	}
+	if _, err := ParseAlternative("two-sided"); err == nil {
+		t.Fatalf("ParseAlternative(%q) succeeded, want error", "two-sided")
+	}
+}
+
+func TestTest(t *testing.T) {
+	tests := []struct {
+		input [4]int
+		alt   Alternative
+		want  Result
+	}{
+		{[4]int{3, 1, 1, 3}, AltGreater,
+			Result{9, 17.0 / 70, math.Log(17.0 / 70), 9.0 / 70}},
+		{[4]int{3, 1, 1, 3}, AltLess,
+			Result{9, 69.0 / 70, math.Log(69.0 / 70), 61.0 / 70}},
+		{[4]int{3, 1, 1, 3}, AltTwoSided,
+			Result{9, 34.0 / 70, math.Log(34.0 / 70), 18.0 / 70}},
+		{[4]int{1000, 0, 0, 1000}, AltGreater,
+			Result{math.Inf(1), 0, -1382.26799353748, 0}},
+	}
+	for _, test := range tests {
+		in := test.input
+		got := Test(in[0], in[1], in[2], in[3], test.alt)
+		if !equalFloat64(got.OddsRatio, test.want.OddsRatio) ||
+			!equalFloat64(got.P, test.want.P) ||
+			!equalFloat64(got.MidP, test.want.MidP) ||
+			gnum.Diff(got.LogP, test.want.LogP) > 1e-9 {
+			t.Errorf("Test(%v,%v)=%v, want %v", in, test.alt, got, test.want)
+		}
+	}
+}
+
+func TestTest_concurrent(t *testing.T) {
+	t.Cleanup(Clear)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := 100 * (i + 1)
+			want := 0.00023568647189489372
+			for range 10 {
+				_, p := TwoSided(60, 10, 30, 25)
+				if !equalFloat64(p, want) {
+					t.Errorf("TwoSided(60,10,30,25)=%v, want %v", p, want)
+				}
+				Greater(n, n, n, n)
+				Clear()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestConditionalOddsRatio(t *testing.T) {
+	tests := []struct {
+		input [4]int
+		want  float64
+	}{
+		{[4]int{3, 1, 1, 3}, 6.408309},    // R: TeaTasting.
+		{[4]int{2, 15, 10, 3}, 0.0469366}, // R: Convictions.
+		{[4]int{2, 2, 2, 2}, 1},
+		{[4]int{0, 2, 2, 2}, 0},
+		{[4]int{2, 0, 2, 2}, math.Inf(1)},
+	}
+	for _, test := range tests {
+		in := test.input
+		got := ConditionalOddsRatio(in[0], in[1], in[2], in[3])
+		if !equalFloat64Tol(got, test.want, 1e-5) {
+			t.Errorf("ConditionalOddsRatio(%v)=%v, want %v", in, got, test.want)
+		}
+	}
+}
+
+func TestConfInt(t *testing.T) {
+	// Values from R, or solved exactly where R's root finding is less
+	// accurate.
+	tests := []struct {
+		input  [4]int
+		alt    Alternative
+		lo, hi float64
+	}{
+		{[4]int{3, 1, 1, 3}, AltGreater, 0.3135693, math.Inf(1)},
+		{[4]int{3, 1, 1, 3}, AltTwoSided, 0.2117356, 626.2435},
+		{[4]int{2, 15, 10, 3}, AltLess, 0, 0.2849601},
+		{[4]int{2, 15, 10, 3}, AltTwoSided, 0.003317164, 0.3631896},
+	}
+	for _, test := range tests {
+		in := test.input
+		lo, hi := ConfInt(in[0], in[1], in[2], in[3], test.alt, 0.95)
+		if !equalFloat64Tol(lo, test.lo, 1e-4) ||
+			!equalFloat64Tol(hi, test.hi, 1e-4) {
+			t.Errorf("ConfInt(%v,%v)=%v,%v, want %v,%v",
+				in, test.alt, lo, hi, test.lo, test.hi)
+		}
+	}
+}
+
+// Checks equality up to a relative tolerance.
+func equalFloat64Tol(a, b, tol float64) bool {
+	if math.IsInf(a, 0) || math.IsInf(b, 0) || b == 0 {
+		return a == b
+	}
+	return math.Abs(a-b) <= tol*math.Abs(b)
+}
+
 func equalFloat64(a, b float64) bool {
 	const delta = 0.00000000001
 	if math.IsInf(a, 1) || math.IsInf(b, 1) {
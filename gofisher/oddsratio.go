@@ -0,0 +1,233 @@
+// Conditional maximum likelihood odds ratio and its confidence intervals.
+
+package gofisher
+
+import (
+	"fmt"
+	"math"
+)
+
+// Returns the log-probabilities of each value of a from h.lo to h.hi, under
+// the noncentral hypergeometric distribution with odds ratio ncp, normalized
+// so that the maximum is 0.
+func (h *hyper) noncentral(ncp float64) []float64 {
+	d := make([]float64, h.hi-h.lo+1)
+	lncp := math.Log(ncp)
+	mx := math.Inf(-1)
+	for i := range d {
+		x := h.lo + i
+		d[i] = h.logp(x) + lncp*float64(x)
+		mx = max(mx, d[i])
+	}
+	for i := range d {
+		d[i] -= mx
+	}
+	return d
+}
+
+// Returns the mean of a under odds ratio ncp.
+func (h *hyper) mean(ncp float64) float64 {
+	if ncp == 0 {
+		return float64(h.lo)
+	}
+	if math.IsInf(ncp, 1) {
+		return float64(h.hi)
+	}
+	sum, wsum := 0.0, 0.0
+	for i, d := range h.noncentral(ncp) {
+		w := math.Exp(d)
+		sum += w
+		wsum += w * float64(h.lo+i)
+	}
+	return wsum / sum
+}
+
+// Returns the probability that a is at most x, or at least x if upper is
+// true, under odds ratio ncp.
+func (h *hyper) cdf(x int, ncp float64, upper bool) float64 {
+	if ncp == 0 {
+		if upper == (x <= h.lo) {
+			return 1
+		}
+		return 0
+	}
+	if math.IsInf(ncp, 1) {
+		if upper == (x <= h.hi) {
+			return 1
+		}
+		return 0
+	}
+	sum, tail := 0.0, 0.0
+	for i, d := range h.noncentral(ncp) {
+		w := math.Exp(d)
+		sum += w
+		if upper && h.lo+i >= x || !upper && h.lo+i <= x {
+			tail += w
+		}
+	}
+	return tail / sum
+}
+
+// ConditionalOddsRatio returns the conditional maximum likelihood estimate
+// of the odds ratio of the table [[a, b], [c, d]], like R's fisher.test.
+// Unlike the sample odds ratio, it is conditioned on the margins of the
+// table. Takes O(n) time per root-finding step.
+func ConditionalOddsRatio(a, b, c, d int) float64 {
+	checkInput(a, b, c, d)
+	h := newHyper(a, b, c, d)
+	if a == h.lo {
+		return 0
+	}
+	if a == h.hi {
+		return math.Inf(1)
+	}
+	x := float64(a)
+	mu := h.mean(1)
+	switch {
+	case mu > x:
+		return findRoot(func(t float64) float64 {
+			return h.mean(t) - x
+		}, 0, 1)
+	case mu < x:
+		return 1 / findRoot(func(t float64) float64 {
+			return h.mean(1/t) - x
+		}, epsilon, 1)
+	default:
+		return 1
+	}
+}
+
+// ConfInt returns the exact confidence interval of the conditional odds
+// ratio of the table [[a, b], [c, d]] with the given confidence level,
+// like R's fisher.test. One-sided alternatives give one-sided intervals.
+func ConfInt(a, b, c, d int, alt Alternative, conf float64) (float64, float64) {
+	checkInput(a, b, c, d)
+	if conf <= 0 || conf >= 1 {
+		panic(fmt.Sprintf("bad confidence level: %v", conf))
+	}
+	h := newHyper(a, b, c, d)
+	switch alt {
+	case AltGreater:
+		return h.lowerLimit(a, 1-conf), math.Inf(1)
+	case AltLess:
+		return 0, h.upperLimit(a, 1-conf)
+	case AltTwoSided:
+		alpha := (1 - conf) / 2
+		return h.lowerLimit(a, alpha), h.upperLimit(a, alpha)
+	default:
+		panic(fmt.Sprintf("bad alternative: %d", alt))
+	}
+}
+
+// Returns the odds ratio under which a is at most x with probability alpha.
+func (h *hyper) upperLimit(x int, alpha float64) float64 {
+	if x == h.hi {
+		return math.Inf(1)
+	}
+	p := h.cdf(x, 1, false)
+	switch {
+	case p < alpha:
+		return findRoot(func(t float64) float64 {
+			return h.cdf(x, t, false) - alpha
+		}, 0, 1)
+	case p > alpha:
+		return 1 / findRoot(func(t float64) float64 {
+			return h.cdf(x, 1/t, false) - alpha
+		}, epsilon, 1)
+	default:
+		return 1
+	}
+}
+
+// Returns the odds ratio under which a is at least x with probability alpha.
+func (h *hyper) lowerLimit(x int, alpha float64) float64 {
+	if x == h.lo {
+		return 0
+	}
+	p := h.cdf(x, 1, true)
+	switch {
+	case p > alpha:
+		return findRoot(func(t float64) float64 {
+			return h.cdf(x, t, true) - alpha
+		}, 0, 1)
+	case p < alpha:
+		return 1 / findRoot(func(t float64) float64 {
+			return h.cdf(x, 1/t, true) - alpha
+		}, epsilon, 1)
+	default:
+		return 1
+	}
+}
+
+// Machine epsilon, the lower bound for inverted odds ratios.
+const epsilon = 0x1p-52
+
+// Returns a root of f in [a, b], where f(a) and f(b) have opposite signs,
+// using Brent's method.
+func findRoot(f func(float64) float64, a, b float64) float64 {
+	const tol = 1e-12
+	fa, fb := f(a), f(b)
+	if fa == 0 {
+		return a
+	}
+	if fb == 0 {
+		return b
+	}
+	c, fc := a, fa
+	d := b - a
+	e := d
+	for range 1000 {
+		if fb*fc > 0 {
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+		if math.Abs(fc) < math.Abs(fb) {
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+		tol1 := 2*epsilon*math.Abs(b) + tol/2
+		xm := (c - b) / 2
+		if math.Abs(xm) <= tol1 || fb == 0 {
+			return b
+		}
+		if math.Abs(e) >= tol1 && math.Abs(fa) > math.Abs(fb) {
+			// Interpolation.
+			s := fb / fa
+			var p, q float64
+			if a == c {
+				p = 2 * xm * s
+				q = 1 - s
+			} else {
+				q = fa / fc
+				r := fb / fc
+				p = s * (2*xm*q*(q-r) - (b-a)*(r-1))
+				q = (q - 1) * (r - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			} else {
+				p = -p
+			}
+			if 2*p < min(3*xm*q-math.Abs(tol1*q), math.Abs(e*q)) {
+				e = d
+				d = p / q
+			} else {
+				d = xm
+				e = d
+			}
+		} else {
+			// Bisection.
+			d = xm
+			e = d
+		}
+		a, fa = b, fb
+		if math.Abs(d) > tol1 {
+			b += d
+		} else {
+			b += math.Copysign(tol1, xm)
+		}
+		fb = f(b)
+	}
+	return b
+}
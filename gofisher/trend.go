@@ -0,0 +1,56 @@
+// Cochran-Armitage trend test.
+
+package gofisher
+
+import (
+	"fmt"
+	"math"
+)
+
+// CochranArmitage returns the z statistic and the two-sided p-value of the
+// Cochran-Armitage test for trend in proportions, over ordered groups with
+// the given numbers of cases and controls. A positive z means that the
+// proportion of cases increases with the scores. If scores is nil, the groups
+// are scored 0, 1, 2 and so on. Same as R's prop.trend.test.
+//
+// Unlike the other tests, the effect is z rather than an odds ratio, since a
+// trend over more than two groups has no single odds ratio; the sign of z
+// gives its direction.
+//
+// Returns NaN for both values if all subjects are cases or all are controls.
+func CochranArmitage(cases, controls []int, scores []float64) (
+	float64, float64) {
+	if len(cases) != len(controls) {
+		panic(fmt.Sprintf("mismatching lengths: %d cases, %d controls",
+			len(cases), len(controls)))
+	}
+	if scores != nil && len(scores) != len(cases) {
+		panic(fmt.Sprintf("mismatching lengths: %d groups, %d scores",
+			len(cases), len(scores)))
+	}
+	score := func(i int) float64 {
+		if scores == nil {
+			return float64(i)
+		}
+		return scores[i]
+	}
+
+	ncases, n := 0.0, 0.0
+	for i := range cases {
+		checkInput(cases[i], controls[i], 0, 0)
+		ncases += float64(cases[i])
+		n += float64(cases[i] + controls[i])
+	}
+	p := ncases / n
+
+	t, sn, sn2 := 0.0, 0.0, 0.0
+	for i := range cases {
+		s := score(i)
+		ni := float64(cases[i] + controls[i])
+		t += s * (float64(cases[i]) - ni*p)
+		sn += s * ni
+		sn2 += s * s * ni
+	}
+	z := t / math.Sqrt(p*(1-p)*(sn2-sn*sn/n))
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
@@ -0,0 +1,64 @@
+package gofisher
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCochranArmitage(t *testing.T) {
+	// R: prop.trend.test(smokers, patients).
+	smokers := []int{83, 90, 129, 70}
+	others := []int{3, 3, 7, 12}
+	tests := []struct {
+		scores []float64
+		wantZ  float64
+		wantP  float64
+	}{
+		{nil, -2.8679125309530877, 0.0041318974776414115},
+		{[]float64{1, 2, 3, 4}, -2.8679125309530877, 0.0041318974776414115},
+		{[]float64{4, 3, 2, 1}, 2.8679125309530877, 0.0041318974776414115},
+	}
+	for _, test := range tests {
+		z, p := CochranArmitage(smokers, others, test.scores)
+		if !equalFloat64(z, test.wantZ) || !equalFloat64(p, test.wantP) {
+			t.Errorf("CochranArmitage(%v,%v,%v)=%f,%f want %f,%f",
+				smokers, others, test.scores, z, p, test.wantZ, test.wantP)
+		}
+	}
+}
+
+func TestCochranArmitage_nan(t *testing.T) {
+	tests := []struct {
+		cases, controls []int
+		scores          []float64
+	}{
+		{[]int{1, 2, 3}, []int{0, 0, 0}, nil},
+		{[]int{0, 0, 0}, []int{4, 5, 6}, nil},
+	}
+	for _, test := range tests {
+		z, p := CochranArmitage(test.cases, test.controls, test.scores)
+		if !math.IsNaN(z) || !math.IsNaN(p) {
+			t.Errorf("CochranArmitage(%v,%v,%v)=%f,%f want NaN,NaN",
+				test.cases, test.controls, test.scores, z, p)
+		}
+	}
+}
+
+func TestCochranArmitage_bad(t *testing.T) {
+	tests := []struct {
+		cases, controls []int
+		scores          []float64
+	}{
+		{[]int{1, 2}, []int{1}, nil},
+		{[]int{1, 2}, []int{1, 2}, []float64{1}},
+		{[]int{1, -2}, []int{1, 2}, nil},
+	}
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			defer func() { recover() }()
+			CochranArmitage(test.cases, test.controls, test.scores)
+			t.Errorf("CochranArmitage(%v,%v,%v) succeeded, want fail",
+				test.cases, test.controls, test.scores)
+		})
+	}
+}
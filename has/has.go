@@ -6,7 +6,6 @@ import (
 	"slices"
 
 	"github.com/fluhus/gostuff/aio"
-	"github.com/fluhus/gostuff/bnry"
 	"github.com/fluhus/gostuff/ptimer"
 	"github.com/fluhus/kwas/iterx"
 	"github.com/fluhus/kwas/kmr/v2"
@@ -26,6 +25,7 @@ func main() {
 
 	files, err := util.ReadLines(aio.Open(*ff))
 	util.Die(err)
+	nsamples := len(files)
 	files, idx := util.ChooseStrings(files, *p-1, *np)
 	fmt.Println("Found", len(files), "files to count")
 
@@ -39,7 +39,7 @@ func main() {
 
 	fout, err := aio.Create(*outFile)
 	util.Die(err)
-	wout := bnry.NewWriter(fout)
+	wout := kmr.NewTupleWriter[kmr.HasHandler](fout)
 
 	fmt.Println("Reading")
 	pt := ptimer.New()
@@ -62,14 +62,15 @@ func main() {
 		for k, v := range has {
 			if len(v) > 0 {
 				slice = append(slice, kmr.HasTuple{
-					Kmer: k, Data: kmr.HasData{Samples: v}})
+					Kmer: k, Data: kmr.HasData{
+						Samples: v, NSamples: nsamples}})
 			}
 		}
 		slices.SortFunc(slice, func(a, b kmr.HasTuple) int {
 			return a.Kmer.Compare(b.Kmer)
 		})
 		for _, kmer := range slice {
-			util.Die(kmer.Encode(wout))
+			util.Die(wout.Write(&kmer))
 			pt.Inc()
 		}
 	}
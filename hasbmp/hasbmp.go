@@ -0,0 +1,57 @@
+// Converts HAS files to bitmap form and back.
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/ptimer"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+)
+
+var (
+	inFile  = flag.String("i", "", "Input files glob (HAS, or bitmap with -r)")
+	outFile = flag.String("o", "", "Output file (bitmap, or HAS with -r)")
+	reverse = flag.Bool("r", false, "Convert bitmap back to HAS")
+)
+
+func main() {
+	util.Die(parseArgs())
+
+	fout, err := aio.Create(*outFile)
+	util.Die(err)
+	pt := ptimer.New()
+	if *reverse {
+		fmt.Println("Converting to HAS")
+		w := kmr.NewTupleWriter[kmr.HasHandler](fout)
+		for t, err := range kmr.IterTuplesFiles[kmr.BitmapHandler](*inFile) {
+			util.Die(err)
+			util.Die(w.Write(kmr.BitmapToHas(t)))
+			pt.Inc()
+		}
+	} else {
+		fmt.Println("Converting to bitmap")
+		w := kmr.NewTupleWriter[kmr.BitmapHandler](fout)
+		for t, err := range kmr.IterTuplesFiles[kmr.HasHandler](*inFile) {
+			util.Die(err)
+			util.Die(w.Write(kmr.HasToBitmap(t)))
+			pt.Inc()
+		}
+	}
+	util.Die(fout.Close())
+	pt.Done()
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *inFile == "" {
+		return fmt.Errorf("empty input path")
+	}
+	if *outFile == "" {
+		return fmt.Errorf("empty output path")
+	}
+	return nil
+}
@@ -18,7 +18,7 @@ import (
 	"github.com/fluhus/biostuff/sequtil"
 	"github.com/fluhus/gostuff/gnum"
 	"github.com/fluhus/gostuff/snm"
-	"github.com/fluhus/kwas/kmr"
+	"github.com/fluhus/kwas/kmr/v2"
 )
 
 const verbose = false // Enable debug prints.
@@ -30,23 +30,21 @@ func loadMatrix(file string) ([][]byte, []kmr.Kmer, error) {
 	}
 	var vals [][]byte
 	var kmers []kmr.Kmer
-	err := kmr.IterTuplesFile(file, &kmr.HasTuple{},
-		func(ht *kmr.HasTuple) error {
-			kmers = append(kmers, ht.Kmer)
-			samples := ht.Data.Samples
-			if len(samples) == 0 {
-				vals = append(vals, nil)
-				return nil
-			}
-			v := make([]byte, samples[len(samples)-1]+1)
-			for _, s := range samples {
-				v[s] = 1
-			}
-			vals = append(vals, v)
-			return nil
-		})
-	if err != nil {
-		return nil, nil, err
+	for ht, err := range kmr.IterTuplesFile[kmr.HasHandler](file) {
+		if err != nil {
+			return nil, nil, err
+		}
+		kmers = append(kmers, ht.Kmer)
+		samples := ht.Data.Samples
+		if len(samples) == 0 {
+			vals = append(vals, nil)
+			continue
+		}
+		v := make([]byte, samples[len(samples)-1]+1)
+		for _, s := range samples {
+			v[s] = 1
+		}
+		vals = append(vals, v)
 	}
 
 	// Make all same length.
@@ -9,7 +9,7 @@ import (
 
 	"github.com/fluhus/biostuff/sequtil"
 	"github.com/fluhus/gostuff/aio"
-	"github.com/fluhus/kwas/kmr"
+	"github.com/fluhus/kwas/kmr/v2"
 	"github.com/fluhus/kwas/util"
 )
 
@@ -31,12 +31,13 @@ func main() {
 	}
 	j := json.NewEncoder(fout)
 
-	util.Die(kmr.IterTuplesFile(*in, &kmr.HasTuple{}, func(t *kmr.HasTuple) error {
-		return j.Encode(map[string]any{
+	for t, err := range kmr.IterTuplesFile[kmr.HasHandler](*in) {
+		util.Die(err)
+		util.Die(j.Encode(map[string]any{
 			"kmer":    string(sequtil.DNAFrom2Bit(nil, t.Kmer[:])[:kmr.K]),
 			"samples": t.Data.Samples,
-		})
-	}))
+		}))
+	}
 	util.Die(fout.Close())
 
 	fmt.Println("Done")
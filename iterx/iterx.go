@@ -8,6 +8,7 @@ import (
 // An Iter wraps an iterator and adds an unread function.
 type Iter[T any] struct {
 	next    func() (T, error, bool) // The underlying iterator
+	stop    func()                  // Stops the underlying iterator
 	head    T                       // The last read element
 	hasHead bool                    // Unread was called
 }
@@ -63,6 +64,12 @@ func (r *Iter[T]) Until(stop func(T) bool) iter.Seq2[T, error] {
 
 // New returns an Unreader with read as its underlying read function.
 func New[T any](seq iter.Seq2[T, error]) *Iter[T] {
-	next, _ := iter.Pull2(seq)
-	return &Iter[T]{next: next, hasHead: false}
+	next, stop := iter.Pull2(seq)
+	return &Iter[T]{next: next, stop: stop, hasHead: false}
+}
+
+// Stop stops the underlying iterator, releasing its resources.
+// Should be called if the iterator is not read to its end.
+func (r *Iter[T]) Stop() {
+	r.stop()
 }
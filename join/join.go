@@ -0,0 +1,249 @@
+// Joins sorted tuple files by kmer.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/fluhus/biostuff/sequtil"
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+)
+
+var (
+	outFile = flag.String("o", "", "Output file (default stdout)")
+	mode    = flag.String("m", "inner", "Join mode: inner, left or anti")
+	format  = flag.String("f", "json", "Output format: json or tsv")
+)
+
+// Join modes by the names given in the mode flag.
+var modes = map[string]kmr.JoinMode{
+	"inner": kmr.InnerJoin,
+	"left":  kmr.LeftJoin,
+	"anti":  kmr.AntiJoin,
+}
+
+// An input file with its tuple type.
+type input struct {
+	typ  string
+	file string
+}
+
+// A kmer with the data of each joined input, nil where there is no match.
+type row struct {
+	kmer kmr.Kmer
+	data []any
+}
+
+func main() {
+	inputs, err := parseArgs()
+	util.Die(err)
+
+	var fout io.WriteCloser = os.Stdout
+	if *outFile != "" {
+		fout, err = aio.Create(*outFile)
+		util.Die(err)
+	}
+	w := bufio.NewWriter(fout)
+	rows := joinAll(inputs, modes[*mode])
+	if *format == "tsv" {
+		err = writeTSV(w, inputs, rows)
+	} else {
+		err = writeJSON(w, rows)
+	}
+	util.Die(err)
+	util.Die(w.Flush())
+	if fout != os.Stdout {
+		util.Die(fout.Close())
+	}
+}
+
+// Joins the first input with each of the others, in order.
+func joinAll(inputs []input, mode kmr.JoinMode) iter.Seq2[row, error] {
+	rows := func(yield func(row, error) bool) {
+		r := row{data: make([]any, len(inputs))}
+		for rec, err := range kmr.IterRecordsFile(inputs[0].typ,
+			inputs[0].file) {
+			if err != nil {
+				yield(row{}, err)
+				return
+			}
+			r.kmer = rec.Kmer
+			r.data[0] = rec.Data
+			if !yield(r, nil) {
+				return
+			}
+		}
+	}
+	for i, in := range inputs[1:] {
+		rows = joinOne(rows, kmr.IterRecordsFile(in.typ, in.file), i+1, mode)
+	}
+	return rows
+}
+
+// Joins rows with the records of a single input, placing the records' data
+// at index i.
+func joinOne(rows iter.Seq2[row, error], recs iter.Seq2[kmr.Record, error],
+	i int, mode kmr.JoinMode) iter.Seq2[row, error] {
+	return func(yield func(row, error) bool) {
+		for j, err := range kmr.Join(rows, recs,
+			func(r row) kmr.Kmer { return r.kmer },
+			func(r kmr.Record) kmr.Kmer { return r.Kmer }, mode) {
+			if err != nil {
+				yield(row{}, err)
+				return
+			}
+			j.Left.data[i] = nil
+			if j.Found {
+				j.Left.data[i] = j.Right.Data
+			}
+			if !yield(j.Left, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Writes rows as JSON lines, with the kmer and the data of each input.
+func writeJSON(w io.Writer, rows iter.Seq2[row, error]) error {
+	j := json.NewEncoder(w)
+	for r, err := range rows {
+		if err != nil {
+			return err
+		}
+		if err := j.Encode(map[string]any{
+			"kmer": kmerString(r.kmer),
+			"data": r.data,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes rows as TSV, with the kmer and the fields of each input's data.
+func writeTSV(w io.Writer, inputs []input, rows iter.Seq2[row, error]) error {
+	header := []string{"kmer"}
+	nfields := make([]int, len(inputs))
+	seen := map[string]int{}
+	for i, in := range inputs {
+		data, err := kmr.NewData(in.typ)
+		if err != nil {
+			return err
+		}
+		prefix := in.typ
+		if seen[in.typ]++; seen[in.typ] > 1 {
+			prefix = fmt.Sprint(in.typ, seen[in.typ])
+		}
+		names, _ := fields(data)
+		for _, name := range names {
+			header = append(header, prefix+"."+name)
+		}
+		nfields[i] = len(names)
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		return err
+	}
+
+	for r, err := range rows {
+		if err != nil {
+			return err
+		}
+		line := []string{kmerString(r.kmer)}
+		for i, data := range r.data {
+			if data == nil {
+				for range nfields[i] {
+					line = append(line, "")
+				}
+				continue
+			}
+			_, vals := fields(data)
+			line = append(line, vals...)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(line, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the names and formatted values of the exported fields of a struct
+// or a struct pointer. Slices are formatted as comma-separated values.
+// Other types are returned as a single field named "data".
+func fields(a any) ([]string, []string) {
+	v := reflect.ValueOf(a)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return []string{"data"}, []string{formatValue(v)}
+	}
+	var names, vals []string
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		names = append(names, f.Name)
+		vals = append(vals, formatValue(v.Field(i)))
+	}
+	return names, vals
+}
+
+// Formats a value for a TSV field.
+func formatValue(v reflect.Value) string {
+	if v.Kind() != reflect.Slice {
+		return fmt.Sprint(v.Interface())
+	}
+	s := make([]string, v.Len())
+	for i := range s {
+		s[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(s, ",")
+}
+
+// Returns the nucleotide sequence of a kmer.
+func kmerString(kmer kmr.Kmer) string {
+	return string(sequtil.DNAFrom2Bit(nil, kmer[:])[:kmr.K])
+}
+
+// Parses and checks the program arguments. Returns the input files.
+func parseArgs() ([]input, error) {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"Usage: join [flags] type:file type:file [type:file ...]")
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"Types:", strings.Join(kmr.Registered(), ", "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if _, ok := modes[*mode]; !ok {
+		return nil, fmt.Errorf("unsupported join mode: %q", *mode)
+	}
+	if *format != "json" && *format != "tsv" {
+		return nil, fmt.Errorf("unsupported format: %q", *format)
+	}
+	if flag.NArg() < 2 {
+		return nil, fmt.Errorf("need at least 2 inputs, got %d", flag.NArg())
+	}
+	var inputs []input
+	for _, arg := range flag.Args() {
+		typ, file, ok := strings.Cut(arg, ":")
+		if !ok || file == "" {
+			return nil, fmt.Errorf("bad input: %q, want type:file", arg)
+		}
+		if _, err := kmr.NewData(typ); err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, input{typ, file})
+	}
+	return inputs, nil
+}
@@ -0,0 +1,173 @@
+// BitmapTuple logic.
+
+package kmr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/fluhus/gostuff/bnry"
+	"github.com/fluhus/kwas/bitmap"
+	"github.com/fluhus/kwas/util"
+)
+
+// Encoding types of bitmap data.
+const (
+	bitmapSparse = 0 // Delta-encoded list.
+	bitmapDense  = 1 // Bitmap bytes.
+	bitmapHeader = 2 // Header with the number of samples.
+)
+
+// BitmapTuple holds a kmer and the sample IDs that have it, as a bitmap set.
+type BitmapTuple = Tuple[BitmapHandler, BitmapData]
+
+type BitmapData struct {
+	Samples bitmap.Set
+
+	// Number of samples of the HAS tuples this was converted from, if
+	// positive. Written once per stream, like in HasData.
+	NSamples int
+}
+
+type BitmapHandler struct{}
+
+// Writes the samples as a delta-encoded list or as bitmap bytes, whichever
+// is shorter.
+func (h BitmapHandler) Encode(c BitmapData, w *bnry.Writer) error {
+	if err := checkNSamples(c.NSamples); err != nil {
+		return err
+	}
+	samples := c.Samples.AppendInts(nil)
+	dense := bitmapBytes(samples)
+	toDiffs(samples)
+	if uvarintLen(uint(len(dense)))+len(dense) < varintsLen(samples) {
+		return w.Write(byte(bitmapDense), dense)
+	}
+	return w.Write(byte(bitmapSparse), samples)
+}
+
+func (h BitmapHandler) Decode(c *BitmapData, r io.ByteReader) error {
+	var typ byte
+	if err := bnry.Read(r, &typ); err != nil {
+		return err
+	}
+	switch typ {
+	case bitmapHeader:
+		var n uint
+		if err := bnry.Read(r, &n); err != nil {
+			return util.NotExpectingEOF(err)
+		}
+		if n > math.MaxInt32 {
+			return fmt.Errorf("bad number of samples: %d", n)
+		}
+		c.NSamples = int(n)
+		return errHeader
+	case bitmapSparse:
+		var s []int
+		if err := bnry.Read(r, &s); err != nil {
+			return err
+		}
+		fromDiffs(s)
+		if len(s) > 0 && s[0] < 0 {
+			return fmt.Errorf("bad bitmap sample: %d", s[0])
+		}
+		for i := range s[min(len(s), 1):] {
+			if s[i] >= s[i+1] {
+				return fmt.Errorf("bitmap samples are not sorted or unique: %d, %d",
+					s[i], s[i+1])
+			}
+		}
+		c.Samples = bitmap.FromSorted(s)
+	case bitmapDense:
+		var b []byte
+		if err := bnry.Read(r, &b); err != nil {
+			return err
+		}
+		words := make([]uint64, (len(b)+7)/8)
+		for i, x := range b {
+			words[i/8] |= uint64(x) << (i % 8 * 8)
+		}
+		c.Samples = bitmap.FromWords(words)
+	default:
+		return fmt.Errorf("bad bitmap encoding type: %d", typ)
+	}
+	return nil
+}
+
+func (h BitmapHandler) Merge(a, b BitmapData) BitmapData {
+	return BitmapData{a.Samples.Union(b.Samples),
+		mergeNSamples(a.NSamples, b.NSamples)}
+}
+
+func (h BitmapHandler) Clone(c BitmapData) BitmapData {
+	return c // Sets are immutable.
+}
+
+func (h BitmapHandler) New() BitmapData {
+	return BitmapData{}
+}
+
+func (h BitmapHandler) header(c BitmapData) int {
+	return c.NSamples
+}
+
+func (h BitmapHandler) encodeHeader(nsamples int, w *bnry.Writer) error {
+	if err := checkNSamples(nsamples); err != nil {
+		return err
+	}
+	return w.Write(byte(bitmapHeader), uint(nsamples))
+}
+
+// HasToBitmap returns a bitmap tuple with the kmer, samples and number of
+// samples of t.
+// Repeated samples are counted once.
+func HasToBitmap(t *HasTuple) *BitmapTuple {
+	samples := slices.Clone(t.Data.Samples)
+	slices.Sort(samples)
+	samples = slices.Compact(samples)
+	return &BitmapTuple{Kmer: t.Kmer, Data: BitmapData{
+		bitmap.FromSorted(samples), t.Data.NSamples}}
+}
+
+// BitmapToHas returns a HAS tuple with the kmer, samples and number of
+// samples of t.
+func BitmapToHas(t *BitmapTuple) *HasTuple {
+	return &HasTuple{Kmer: t.Kmer, Data: HasData{
+		Samples: t.Data.Samples.AppendInts(nil), NSamples: t.Data.NSamples}}
+}
+
+// Returns the bytes of a bitmap of the given sorted elements.
+func bitmapBytes(a []int) []byte {
+	if len(a) == 0 {
+		return nil
+	}
+	b := make([]byte, a[len(a)-1]/8+1)
+	for _, x := range a {
+		b[x/8] |= 1 << (x % 8)
+	}
+	return b
+}
+
+// Returns the number of bytes of the given int slice in bnry encoding.
+func varintsLen(a []int) int {
+	n := uvarintLen(uint(len(a)))
+	for _, x := range a {
+		n += varintLen(x)
+	}
+	return n
+}
+
+// Returns the number of bytes of the given int in bnry encoding.
+func varintLen(x int) int {
+	var buf [binary.MaxVarintLen64]byte
+	return len(binary.AppendVarint(buf[:0], int64(x)))
+}
+
+// Returns the number of bytes of the given uint in bnry encoding.
+func uvarintLen(x uint) int {
+	var buf [binary.MaxVarintLen64]byte
+	return len(binary.AppendUvarint(buf[:0], uint64(x)))
+}
@@ -0,0 +1,70 @@
+// ClusterTuple logic.
+
+package kmr
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/fluhus/gostuff/bnry"
+)
+
+// ClusterTuple holds a cluster center kmer and the kmers that were collapsed
+// into it.
+type ClusterTuple = Tuple[ClusterHandler, ClusterData]
+
+type ClusterData struct {
+	Members []Kmer
+}
+
+type ClusterHandler struct{}
+
+func (h ClusterHandler) Encode(c ClusterData, w *bnry.Writer) error {
+	buf := make([]byte, 0, len(c.Members)*K2B)
+	for _, kmer := range c.Members {
+		buf = append(buf, kmer[:]...)
+	}
+	return w.Write(buf)
+}
+
+func (h ClusterHandler) Decode(c *ClusterData, r io.ByteReader) error {
+	var buf []byte
+	if err := bnry.Read(r, &buf); err != nil {
+		return err
+	}
+	if len(buf)%K2B != 0 {
+		return fmt.Errorf("bad members length: %d, want a multiple of %d",
+			len(buf), K2B)
+	}
+	c.Members = c.Members[:0]
+	for i := 0; i < len(buf); i += K2B {
+		c.Members = append(c.Members, Kmer(buf[i:i+K2B]))
+	}
+	return nil
+}
+
+func (h ClusterHandler) Merge(a, b ClusterData) ClusterData {
+	return ClusterData{append(a.Members, b.Members...)}
+}
+
+func (h ClusterHandler) Clone(c ClusterData) ClusterData {
+	return ClusterData{slices.Clone(c.Members)}
+}
+
+func (h ClusterHandler) New() ClusterData {
+	return ClusterData{}
+}
+
+// ReadClustersFiles returns a map from each center to its cluster members,
+// from all the cluster files matching the given glob pattern.
+func ReadClustersFiles(glob string) (map[Kmer][]Kmer, error) {
+	result := map[Kmer][]Kmer{}
+	for t, err := range IterTuplesFiles[ClusterHandler](glob) {
+		if err != nil {
+			return nil, err
+		}
+		result[t.Kmer] = append(result[t.Kmer], t.Data.Members...)
+	}
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package kmr
+
+import (
+	"slices"
+	"testing"
+)
+
+// Files in testdata were written by the removed v1 package (kwas/kmr), and
+// should decode the same in this package.
+
+func TestV1Compat_count(t *testing.T) {
+	want := []*CountTuple{
+		{Kmer: Kmer{0, 2, 3, 4, 5}, Data: CountData{1}},
+		{Kmer: Kmer{1, 2, 3, 4, 5}, Data: CountData{300}},
+		{Kmer: Kmer{2, 2, 3, 4, 5}, Data: CountData{70000}},
+	}
+	var got []*CountTuple
+	for tup, err := range IterTuplesFile[CountHandler]("testdata/v1_count.bin") {
+		if err != nil {
+			t.Fatalf("IterTuplesFile() failed: %v", err)
+		}
+		got = append(got, tup.Clone())
+	}
+	if !slices.EqualFunc(got, want, countTuplesEqual) {
+		t.Fatalf("IterTuplesFile()=%v, want %v", got, want)
+	}
+}
+
+func TestV1Compat_has(t *testing.T) {
+	want := []*HasTuple{
+		{Kmer: Kmer{0, 6, 7}, Data: HasData{Samples: []int{0}}},
+		{Kmer: Kmer{1, 6, 7}, Data: HasData{Samples: []int{1, 3, 5}}},
+		{Kmer: Kmer{2, 6, 7}, Data: HasData{Samples: []int{}}},
+		{Kmer: Kmer{3, 6, 7}, Data: HasData{Samples: []int{2, 1000, 100000}}},
+	}
+	var got []*HasTuple
+	for tup, err := range IterTuplesFile[HasHandler]("testdata/v1_has.bin") {
+		if err != nil {
+			t.Fatalf("IterTuplesFile() failed: %v", err)
+		}
+		got = append(got, tup.Clone())
+	}
+	if !slices.EqualFunc(got, want, hasTuplesEqual) {
+		t.Fatalf("IterTuplesFile()=%v, want %v", got, want)
+	}
+}
+
+func TestV1Compat_profile(t *testing.T) {
+	want := &ProfileData{}
+	want.P.Fill([]byte("ACGTACGTACGTACGTACGTAC"), 1)
+	want.C[3] = 2
+	want.C[50] = 7
+
+	n := 0
+	for tup, err := range IterTuplesFile[ProfileHandler]("testdata/v1_profile.bin") {
+		if err != nil {
+			t.Fatalf("IterTuplesFile() failed: %v", err)
+		}
+		if tup.Kmer != (Kmer{9, 9}) || *tup.Data != *want {
+			t.Fatalf("IterTuplesFile()=%v, want %v %v", tup.Kmer, Kmer{9, 9}, want)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("IterTuplesFile() returned %v tuples, want 1", n)
+	}
+}
+
+func TestV1Compat_dump(t *testing.T) {
+	want := []Kmer{{1, 2, 3, 4, 5}, {1, 2, 3, 4, 6}, {1, 7}, {200, 0, 0, 0, 1}}
+	var got []Kmer
+	for kmer, err := range IterKmersFile("testdata/v1_dump.bin") {
+		if err != nil {
+			t.Fatalf("IterKmersFile() failed: %v", err)
+		}
+		got = append(got, kmer)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("IterKmersFile()=%v, want %v", got, want)
+	}
+}
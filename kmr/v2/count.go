@@ -17,22 +17,22 @@ type CountData struct {
 
 type CountHandler struct{}
 
-func (h CountHandler) encode(c CountData, w *bnry.Writer) error {
+func (h CountHandler) Encode(c CountData, w *bnry.Writer) error {
 	return w.Write(c.Count)
 }
 
-func (h CountHandler) decode(c *CountData, r io.ByteReader) error {
+func (h CountHandler) Decode(c *CountData, r io.ByteReader) error {
 	return bnry.Read(r, &c.Count)
 }
 
-func (h CountHandler) merge(a, b CountData) CountData {
+func (h CountHandler) Merge(a, b CountData) CountData {
 	return CountData{a.Count + b.Count}
 }
 
-func (h CountHandler) clone(c CountData) CountData {
+func (h CountHandler) Clone(c CountData) CountData {
 	return c
 }
 
-func (h CountHandler) new() CountData {
+func (h CountHandler) New() CountData {
 	return CountData{}
 }
@@ -3,42 +3,61 @@
 package kmr
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 	"math"
-	"math/rand"
+	"math/bits"
 	"slices"
-	"time"
 
 	"github.com/fluhus/gostuff/bnry"
-	"github.com/fluhus/gostuff/gnum"
-	"github.com/fluhus/gostuff/snm"
-	"github.com/fluhus/kwas/regress"
+	"github.com/fluhus/kwas/util"
 )
 
+// Markers that are written in place of the samples' length, which can never
+// be this large.
 const (
-	useLinSort     = false
-	useRegressSort = false
+	complementMarker = math.MaxInt64     // Tuple is encoded by absent samples.
+	headerMarker     = math.MaxInt64 - 1 // Header with the number of samples.
 )
 
+// Marks merged data whose inputs disagree on the number of samples.
+const badNSamples = -1
+
 // HasTuple holds a kmer and the sample IDs that have it.
 type HasTuple = Tuple[HasHandler, HasData]
 
 type HasData struct {
 	Samples      []int
 	SortOnEncode bool // If true, will sort before encoding.
+
+	// If positive, samples are 0 to NSamples-1, and tuples are encoded by
+	// their absent samples when that is shorter. NSamples is written once
+	// per stream in a header record, so tuples with NSamples should be
+	// written with a TupleWriter.
+	NSamples int
 }
 
 type HasHandler struct{}
 
-func (h HasHandler) encode(c HasData, w *bnry.Writer) error {
+func (h HasHandler) Encode(c HasData, w *bnry.Writer) error {
+	if err := checkNSamples(c.NSamples); err != nil {
+		return err
+	}
 	if c.SortOnEncode {
-		if useLinSort && len(c.Samples) > 2000 {
-			linearSort(c.Samples)
-		} else if useRegressSort {
-			regressSort(c.Samples)
-		} else {
-			slices.Sort(c.Samples)
+		sortSamples(c.Samples)
+	}
+	if c.NSamples > 0 && len(c.Samples)*2 > c.NSamples {
+		absent, err := complement(c.Samples, c.NSamples)
+		if err != nil {
+			return err
+		}
+		toDiffs(absent)
+		toDiffs(c.Samples)
+		n := varintsLen(c.Samples)
+		fromDiffs(c.Samples)
+		if uvarintLen(complementMarker)+varintsLen(absent) < n {
+			return w.Write(uint(complementMarker), absent)
 		}
 	}
 	toDiffs(c.Samples)
@@ -47,29 +66,137 @@ func (h HasHandler) encode(c HasData, w *bnry.Writer) error {
 	return err
 }
 
-func (h HasHandler) decode(c *HasData, r io.ByteReader) error {
-	s := c.Samples[:0]
-	if err := bnry.Read(r, &s); err != nil {
+// Decode reads the samples of a tuple. After a header record, it keeps the
+// header's number of samples in c and returns errHeader.
+func (h HasHandler) Decode(c *HasData, r io.ByteReader) error {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
 		return err
 	}
-	fromDiffs(s)
-	c.Samples = s
-	return nil
+	switch n {
+	case headerMarker:
+		ns, err := binary.ReadUvarint(r)
+		if err != nil {
+			return util.NotExpectingEOF(err)
+		}
+		if ns > math.MaxInt32 {
+			return fmt.Errorf("bad number of samples: %d", ns)
+		}
+		c.NSamples = int(ns)
+		return errHeader
+	case complementMarker:
+		if c.NSamples == 0 {
+			return fmt.Errorf("found complemented samples " +
+				"before a number of samples header")
+		}
+		s, err := decodeAbsent(r, c.Samples[:0], c.NSamples)
+		if err != nil {
+			return err
+		}
+		c.Samples = s
+		return nil
+	default:
+		s := c.Samples[:0]
+		for range n {
+			x, err := binary.ReadVarint(r)
+			if err != nil {
+				return util.NotExpectingEOF(err)
+			}
+			s = append(s, int(x))
+		}
+		fromDiffs(s)
+		c.Samples = s
+		return nil
+	}
 }
 
-func (h HasHandler) merge(a, b HasData) HasData {
+// Merges the samples of a and b. If they disagree on the number of samples,
+// encoding the result fails.
+func (h HasHandler) Merge(a, b HasData) HasData {
 	if a.SortOnEncode != b.SortOnEncode {
 		panic(fmt.Sprintf("inputs disagree on SortOnEncode: %v, %v",
 			a.SortOnEncode, b.SortOnEncode))
 	}
-	return HasData{append(a.Samples, b.Samples...), a.SortOnEncode}
+	return HasData{append(a.Samples, b.Samples...), a.SortOnEncode,
+		mergeNSamples(a.NSamples, b.NSamples)}
+}
+
+// Returns the number of samples of merged data, or badNSamples if a and b
+// disagree.
+func mergeNSamples(a, b int) int {
+	if min(a, b) < 0 || a > 0 && b > 0 && a != b {
+		return badNSamples
+	}
+	return max(a, b)
+}
+
+func (h HasHandler) Clone(c HasData) HasData {
+	return HasData{slices.Clone(c.Samples), c.SortOnEncode, c.NSamples}
+}
+
+func (h HasHandler) header(c HasData) int {
+	return c.NSamples
+}
+
+func (h HasHandler) encodeHeader(nsamples int, w *bnry.Writer) error {
+	if err := checkNSamples(nsamples); err != nil {
+		return err
+	}
+	return w.Write(uint(headerMarker), uint(nsamples))
+}
+
+// Returns an error if n is not a valid number of samples.
+func checkNSamples(n int) error {
+	if n == badNSamples {
+		return fmt.Errorf("inputs disagree on the number of samples")
+	}
+	if n < 0 {
+		return fmt.Errorf("bad number of samples: %d", n)
+	}
+	return nil
 }
 
-func (h HasHandler) clone(c HasData) HasData {
-	return HasData{slices.Clone(c.Samples), c.SortOnEncode}
+// Reads a delta-encoded list of absent samples, and appends to buf the
+// samples in 0 to nsamples-1 that are not in it.
+func decodeAbsent(r io.ByteReader, buf []int, nsamples int) ([]int, error) {
+	var absent []int
+	if err := bnry.Read(r, &absent); err != nil {
+		return nil, util.NotExpectingEOF(err)
+	}
+	fromDiffs(absent)
+	for i := range nsamples {
+		if len(absent) > 0 && absent[0] == i {
+			absent = absent[1:]
+			continue
+		}
+		buf = append(buf, i)
+	}
+	if len(absent) > 0 {
+		return nil, fmt.Errorf("absent sample %d is out of range %d, "+
+			"or samples are not sorted", absent[0], nsamples)
+	}
+	return buf, nil
 }
 
-func (h HasHandler) new() HasData {
+// Returns the samples in 0 to n-1 that are not in a, sorted.
+func complement(a []int, n int) ([]int, error) {
+	has := make([]bool, n)
+	for _, x := range a {
+		if x < 0 || x >= n {
+			return nil, fmt.Errorf("sample %d is out of range %d", x, n)
+		}
+		has[x] = true
+	}
+	var result []int
+	for i, b := range has {
+		if !b {
+			result = append(result, i)
+		}
+	}
+	return result, nil
+}
+
+func (h HasHandler) New() HasData {
 	return HasData{SortOnEncode: true}
 }
 
@@ -94,140 +221,81 @@ func toDiffs(a []int) {
 	}
 }
 
-func linearSort(a []int) {
-	if len(a) == 0 {
-		return
-	}
-	mn, mx := gnum.Min(a), gnum.Max(a)
-	b := make([]bool, mx-mn+1)
-	for _, s := range a {
-		b[s-mn] = true
-	}
-	a = a[:0]
-	for i := range b {
-		if b[i] {
-			a = append(a, i+mn)
-		}
-	}
-}
-
-var (
-	rsLinX  [][]float64
-	rsLinY  []float64
-	rsLinB  []float64
-	rsSortX []float64
-	rsSortY []float64
-	rsSortB float64
-
-	logmap = snm.NewDefaultMap(func(i int) float64 {
-		return math.Log(float64(i))
-	})
+// Thresholds for choosing a sorting algorithm in sortSamples.
+const (
+	minAdaptiveSort = 256 // Shorter slices use comparison sort.
+	maxBitmapSpan   = 64  // Maximal span per element for bitmap sort.
+	minRadixSort    = 2048
 )
 
-func regressSort(a []int) {
-	n := len(a)
-	if n == 0 {
-		return
-	}
-	mn, mx := gnum.Min(a), gnum.Max(a)
-	// nlogn := float64(n) * logmap.Get(n) // math.Log(float64(n))
-	nlogn := float64(n * logint(n))
-	span := mx - mn + 1
-
-	pLin, pSort := 1.0, 1.0
-	if len(rsLinY) >= 10 && len(rsSortY) >= 10 { // Enough data to make a prediction.
-		pLin = 1 / (float64(n)*rsLinB[0] + float64(span)*rsLinB[1])
-		pSort = 1 / (nlogn * rsSortB)
-	}
-
-	if rand.Float64() < pSort/(pSort+pLin) {
-		// t := time.Now()
+// Sorts a slice of samples, choosing an algorithm by its length and span.
+// Dense slices get a bitmap sort, long sparse slices get a radix sort, and
+// the rest get a comparison sort.
+func sortSamples(a []int) {
+	if len(a) < minAdaptiveSort {
 		slices.Sort(a)
-		// d := time.Since(t).Seconds()
-		// rsSortX = append(rsSortX, nlogn)
-		// rsSortY = append(rsSortY, d)
-		// if len(rsSortY) == cap(rsSortY) {
-		// 	rsSortB = regress.Regression1(rsSortX, rsSortY)
-		// }
 		return
 	}
-
-	// t := time.Now()
-	b := make([]bool, span)
-	for _, s := range a {
-		b[s-mn] = true
+	mn, mx := a[0], a[0]
+	for _, x := range a[1:] {
+		mn = min(mn, x)
+		mx = max(mx, x)
 	}
-	a = a[:0]
-	for i := range b {
-		if b[i] {
-			a = append(a, i+mn)
+	span := uint(mx - mn)
+	switch {
+	case span/maxBitmapSpan < uint(len(a)):
+		if !bitmapSort(a, mn, span) {
+			slices.Sort(a)
 		}
+	case len(a) >= minRadixSort:
+		radixSort(a, mn, span)
+	default:
+		slices.Sort(a)
 	}
-	// d := time.Since(t).Seconds()
-	// rsLinX = append(rsLinX, []float64{float64(n), float64(span)})
-	// rsLinY = append(rsLinY, d)
-	// if len(rsLinY) == cap(rsLinY) {
-	// 	rsLinB = regress.Regression2(rsLinX, rsLinY)
-	// }
 }
 
-func regressSortTrain(a []int) {
-	n := len(a)
-	if n == 0 {
-		return
-	}
-	mn, mx := gnum.Min(a), gnum.Max(a)
-	nlogn := float64(n * logint(n))
-	span := mx - mn + 1
-
-	aa := slices.Clone(a)
-	t := time.Now()
-	slices.Sort(aa)
-	d := time.Since(t).Seconds()
-	rsSortX = append(rsSortX, nlogn)
-	rsSortY = append(rsSortY, d)
-	if len(rsSortY) == cap(rsSortY) {
-		rsSortB = regress.Regression1(rsSortX, rsSortY)
-	}
-
-	a = slices.Clone(a)
-	t = time.Now()
-	b := make([]bool, span)
-	for _, s := range a {
-		b[s-mn] = true
+// Sorts a using a bitmap of its span. Returns false and leaves a unsorted if
+// it has duplicates.
+func bitmapSort(a []int, mn int, span uint) bool {
+	words := make([]uint64, span/64+1)
+	for _, x := range a {
+		i := uint(x - mn)
+		if words[i/64]&(1<<(i%64)) != 0 { // Duplicate.
+			return false
+		}
+		words[i/64] |= 1 << (i % 64)
 	}
 	a = a[:0]
-	for i := range b {
-		if b[i] {
-			a = append(a, i+mn)
+	for i, w := range words {
+		for w != 0 {
+			a = append(a, mn+i*64+bits.TrailingZeros64(w))
+			w &= w - 1
 		}
 	}
-	d = time.Since(t).Seconds()
-	rsLinX = append(rsLinX, []float64{float64(n), float64(span)})
-	rsLinY = append(rsLinY, d)
-	if len(rsLinY) == cap(rsLinY) {
-		rsLinB = regress.Regression2(rsLinX, rsLinY)
-	}
+	return true
 }
 
-func init() {
-	if useRegressSort {
-		t := time.Now()
-		for _, n := range []int{10, 100, 1000, 10000, 100000, 1000000} {
-			a := rand.Perm(n)
-			for i := 1; i <= 10; i++ {
-				regressSortTrain(a[:len(a)*i/10])
-			}
+// Sorts a using a least-significant-digit radix sort on 8-bit digits of the
+// offsets from mn.
+func radixSort(a []int, mn int, span uint) {
+	buf := make([]int, len(a))
+	src, dst := a, buf
+	for shift := 0; span>>shift > 0; shift += 8 {
+		var counts [257]int
+		for _, x := range src {
+			counts[(uint(x-mn)>>shift)&0xff+1]++
 		}
-		fmt.Println("Init took", time.Since(t))
+		for i := range counts[1:] {
+			counts[i+1] += counts[i]
+		}
+		for _, x := range src {
+			d := (uint(x-mn) >> shift) & 0xff
+			dst[counts[d]] = x
+			counts[d]++
+		}
+		src, dst = dst, src
 	}
-}
-
-func logint(n int) int {
-	a := 0
-	for n > 0 {
-		a++
-		n /= 2
+	if &src[0] != &a[0] {
+		copy(a, src)
 	}
-	return a
 }
@@ -0,0 +1,70 @@
+package kmr
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestSortSamples(t *testing.T) {
+	tests := []struct {
+		n    int // Length.
+		span int // Range of values.
+	}{
+		{0, 1}, {1, 1}, {10, 5}, {100, 1000},
+		{300, 400}, {1000, 1000}, {1000, 100000},
+		{3000, 3000}, {3000, 10000000}, {5000, 1 << 40},
+	}
+	for _, test := range tests {
+		for _, offset := range []int{0, -500, 1 << 20} {
+			a := make([]int, test.n)
+			for i := range a {
+				a[i] = rand.Intn(test.span) + offset
+			}
+			want := slices.Clone(a)
+			slices.Sort(want)
+			sortSamples(a)
+			if !slices.Equal(a, want) {
+				t.Errorf("sortSamples(n=%v,span=%v,offset=%v)=%v, want %v",
+					test.n, test.span, offset, a, want)
+			}
+		}
+	}
+}
+
+func TestSortSamples_unique(t *testing.T) {
+	for _, n := range []int{300, 1000, 5000} {
+		for _, span := range []int{n, n * 2, n * 100, n * 10000} {
+			a := rand.Perm(span)[:n]
+			want := slices.Clone(a)
+			slices.Sort(want)
+			sortSamples(a)
+			if !slices.Equal(a, want) {
+				t.Errorf("sortSamples(n=%v,span=%v)=%v, want %v",
+					n, span, a, want)
+			}
+		}
+	}
+}
+
+func BenchmarkSortSamples(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000, 100000} {
+		for _, density := range []int{1, 10, 1000} {
+			s := rand.Perm(n * density)[:n]
+			buf := make([]int, n)
+			b.Run(fmt.Sprint("adaptive.", n, ".", density), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					copy(buf, s)
+					sortSamples(buf)
+				}
+			})
+			b.Run(fmt.Sprint("slices.", n, ".", density), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					copy(buf, s)
+					slices.Sort(buf)
+				}
+			})
+		}
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"io"
 	"iter"
 
-	"github.com/fluhus/gostuff/bnry"
 	"github.com/fluhus/gostuff/heaps"
 	"github.com/fluhus/gostuff/ptimer"
 )
@@ -106,7 +105,7 @@ func (m *Merger[H, T]) nextMin() error {
 
 // Dump merges all the remaining kmer tuples and writes them to the given writer.
 func (m *Merger[H, T]) Dump(w io.Writer) error {
-	bw := bnry.NewWriter(w)
+	tw := NewTupleWriter[H](w)
 	pt := ptimer.NewFunc(func(i int) string {
 		return fmt.Sprintf("%d kmers dumped", i)
 	})
@@ -116,7 +115,7 @@ func (m *Merger[H, T]) Dump(w io.Writer) error {
 		if err != nil {
 			return err
 		}
-		err = tup.Encode(bw)
+		err = tw.Write(tup)
 		if err != nil {
 			return err
 		}
@@ -22,7 +22,7 @@ func IterTuplesFile[H KmerDataHandler[T], T any](
 		defer f.Close()
 		for err = t.Decode(f); err == nil; err = t.Decode(f) {
 			if !yield(t, nil) {
-				break
+				return
 			}
 		}
 		if err != io.EOF {
@@ -39,7 +39,7 @@ func IterTuplesReader[H KmerDataHandler[T], T any](
 		var err error
 		for err = t.Decode(r); err == nil; err = t.Decode(r) {
 			if !yield(t, nil) {
-				break
+				return
 			}
 		}
 		if err != io.EOF {
@@ -0,0 +1,124 @@
+// Joining sorted tuple streams by kmer.
+
+package kmr
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/fluhus/kwas/iterx"
+)
+
+// JoinMode selects which left elements a join yields.
+type JoinMode int
+
+const (
+	InnerJoin JoinMode = iota // Left elements that have a match.
+	LeftJoin                  // All left elements, matched or not.
+	AntiJoin                  // Left elements that have no match.
+)
+
+// Joined is a left element with its matching right element.
+type Joined[A, B any] struct {
+	Left  A
+	Right B    // Zero if there is no match.
+	Found bool // Whether Right is a match.
+}
+
+// Join matches each element of left with the element of right that has the
+// same kmer, in a single pass over both streams.
+// Both streams should be sorted by kmer with no duplicates, like the output
+// of [Merger.Dump]; otherwise an error is yielded. The right stream is read
+// only as far as the left stream's kmers require.
+// The yielded elements are valid until the next iteration, since the
+// underlying iterators may reuse them.
+func Join[A, B any](left iter.Seq2[A, error], right iter.Seq2[B, error],
+	leftKey func(A) Kmer, rightKey func(B) Kmer, mode JoinMode,
+) iter.Seq2[Joined[A, B], error] {
+	return func(yield func(Joined[A, B], error) bool) {
+		if mode < InnerJoin || mode > AntiJoin {
+			yield(Joined[A, B]{}, fmt.Errorf("bad join mode: %d", mode))
+			return
+		}
+		r := iterx.New(checkSorted(right, rightKey, "right"))
+		defer r.Stop()
+
+		for a, err := range checkSorted(left, leftKey, "left") {
+			if err != nil {
+				yield(Joined[A, B]{}, err)
+				return
+			}
+			k := leftKey(a)
+
+			// Skip right elements that come before k.
+			for _, err := range r.Until(func(b B) bool {
+				return !rightKey(b).Less(k)
+			}) {
+				if err != nil {
+					yield(Joined[A, B]{}, err)
+					return
+				}
+			}
+
+			j := Joined[A, B]{Left: a}
+			b, err, ok := r.Next()
+			if err != nil {
+				yield(Joined[A, B]{}, err)
+				return
+			}
+			if ok {
+				r.Unread()
+				if rightKey(b) == k {
+					j.Right, j.Found = b, true
+				}
+			}
+
+			if mode == InnerJoin && !j.Found || mode == AntiJoin && j.Found {
+				continue
+			}
+			if !yield(j, nil) {
+				return
+			}
+		}
+	}
+}
+
+// JoinTuples joins two tuple streams by kmer. See [Join].
+func JoinTuples[HA KmerDataHandler[A], A any, HB KmerDataHandler[B], B any](
+	left iter.Seq2[*Tuple[HA, A], error], right iter.Seq2[*Tuple[HB, B], error],
+	mode JoinMode,
+) iter.Seq2[Joined[*Tuple[HA, A], *Tuple[HB, B]], error] {
+	return Join(left, right, tupleKmer, tupleKmer, mode)
+}
+
+// Returns the kmer of a tuple.
+func tupleKmer[H KmerDataHandler[T], T any](t *Tuple[H, T]) Kmer {
+	return t.Kmer
+}
+
+// Yields the elements of seq, or an error if their kmers are not strictly
+// increasing. Name identifies the stream in the error.
+func checkSorted[T any](seq iter.Seq2[T, error], key func(T) Kmer,
+	name string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var last Kmer
+		i := 0
+		for t, err := range seq {
+			if err == nil {
+				k := key(t)
+				if i > 0 && !last.Less(k) {
+					var zero T
+					yield(zero, fmt.Errorf("%s stream is not sorted or has "+
+						"duplicates: element #%d is not greater than #%d",
+						name, i, i-1))
+					return
+				}
+				last = k
+				i++
+			}
+			if !yield(t, err) {
+				return
+			}
+		}
+	}
+}
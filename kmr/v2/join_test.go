@@ -0,0 +1,95 @@
+package kmr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinTuples(t *testing.T) {
+	left := []*HasTuple{
+		{Kmer: Kmer{1}, Data: HasData{Samples: []int{0}}},
+		{Kmer: Kmer{3}, Data: HasData{Samples: []int{1}}},
+		{Kmer: Kmer{4}, Data: HasData{Samples: []int{2}}},
+		{Kmer: Kmer{7}, Data: HasData{Samples: []int{3}}},
+	}
+	right := []*CountTuple{
+		{Kmer: Kmer{0}, Data: CountData{Count: 10}},
+		{Kmer: Kmer{3}, Data: CountData{Count: 30}},
+		{Kmer: Kmer{5}, Data: CountData{Count: 50}},
+		{Kmer: Kmer{7}, Data: CountData{Count: 70}},
+	}
+	tests := []struct {
+		mode JoinMode
+		want [][2]int // Left sample, right count or -1.
+	}{
+		{InnerJoin, [][2]int{{1, 30}, {3, 70}}},
+		{LeftJoin, [][2]int{{0, -1}, {1, 30}, {2, -1}, {3, 70}}},
+		{AntiJoin, [][2]int{{0, -1}, {2, -1}}},
+	}
+	for _, test := range tests {
+		var got [][2]int
+		for j, err := range JoinTuples(hasSeq(left), countSeq(right),
+			test.mode) {
+			if err != nil {
+				t.Fatalf("JoinTuples(%v) failed: %v", test.mode, err)
+			}
+			c := -1
+			if j.Found {
+				c = j.Right.Data.Count
+			}
+			got = append(got, [2]int{j.Left.Data.Samples[0], c})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("JoinTuples(%v)=%v, want %v", test.mode, got, test.want)
+		}
+	}
+}
+
+func TestJoinTuples_unsorted(t *testing.T) {
+	sorted := []*HasTuple{{Kmer: Kmer{1}}, {Kmer: Kmer{2}}, {Kmer: Kmer{3}}}
+	unsorted := []*HasTuple{{Kmer: Kmer{2}}, {Kmer: Kmer{1}}}
+	dups := []*HasTuple{{Kmer: Kmer{1}}, {Kmer: Kmer{1}}}
+	tests := [][2][]*HasTuple{
+		{unsorted, sorted},
+		{sorted, unsorted},
+		{dups, sorted},
+		{sorted, dups},
+	}
+	for _, test := range tests {
+		var err error
+		for _, err = range JoinTuples(hasSeq(test[0]), hasSeq(test[1]),
+			LeftJoin) {
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			t.Errorf("JoinTuples(%v, %v) succeeded, want error",
+				test[0], test[1])
+		}
+	}
+}
+
+func TestJoinTuples_break(t *testing.T) {
+	left := []*HasTuple{{Kmer: Kmer{1}}, {Kmer: Kmer{2}}, {Kmer: Kmer{3}}}
+	n := 0
+	for range JoinTuples(hasSeq(left), hasSeq(left), InnerJoin) {
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	if n != 2 {
+		t.Fatalf("got %d joined tuples, want 2", n)
+	}
+}
+
+func countSeq(tuples []*CountTuple) func(yield func(*CountTuple, error) bool) {
+	return func(yield func(*CountTuple, error) bool) {
+		for _, t := range tuples {
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
@@ -3,6 +3,8 @@ package kmr
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/fluhus/biostuff/sequtil"
 	"github.com/fluhus/gostuff/aio"
@@ -42,6 +44,42 @@ func ReadKmersLines(file string) (KmerSet, error) {
 	return m, nil
 }
 
+// ReadKmersList reads a list of kmers in file order. If dump is true, the
+// file is a dump whose kmers should be sorted and unique. Otherwise it is a
+// text file with a kmer per line, and kmers are converted to their canonical
+// forms.
+func ReadKmersList(file string, dump bool) ([]Kmer, error) {
+	if dump {
+		var result []Kmer
+		for kmer, err := range IterKmersFile(file) {
+			if err != nil {
+				return nil, err
+			}
+			if len(result) > 0 && result[len(result)-1].Compare(kmer) >= 0 {
+				return nil, fmt.Errorf(
+					"kmers are not sorted and unique at #%d", len(result)+1)
+			}
+			result = append(result, kmer)
+		}
+		return slices.Clip(result), nil
+	}
+
+	lines, err := util.ReadLines(aio.Open(file))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Kmer, 0, len(lines))
+	for i, line := range lines {
+		if len(line) != K || strings.Trim(line, "ACGT") != "" {
+			return nil, fmt.Errorf("line %d: bad kmer: %q", i+1, line)
+		}
+		CanonicalKmers([]byte(line), func(kmer Kmer, _ int, _ bool) {
+			result = append(result, kmer)
+		})
+	}
+	return result, nil
+}
+
 // Less compares the receiver to the argument lexicographically.
 func (a Kmer) Less(b Kmer) bool {
 	for i := range a {
@@ -0,0 +1,75 @@
+package kmr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fluhus/gostuff/aio"
+	"golang.org/x/exp/slices"
+)
+
+func TestReadKmersList_text(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "kmers.txt")
+	input := "AAAAAAAAAAAAAAAAAAAC\nGTTTTTTTTTTTTTTTTTTT\nCCCCCCCCCCCCCCCCCCCC\n"
+	if err := os.WriteFile(file, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := []Kmer{{0, 0, 0, 0, 1}, {0, 0, 0, 0, 1},
+		{85, 85, 85, 85, 85}}
+	got, err := ReadKmersList(file, false)
+	if err != nil {
+		t.Fatalf("ReadKmersList(%q) failed: %v", input, err)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ReadKmersList(%q)=%v, want %v", input, got, want)
+	}
+
+	input = "AAAAAAAAAAAAAAAAAAAN\n"
+	if err := os.WriteFile(file, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadKmersList(file, false); err == nil {
+		t.Fatalf("ReadKmersList(%q) succeeded, want error", input)
+	}
+}
+
+func TestReadKmersList_dump(t *testing.T) {
+	tests := []struct {
+		input []Kmer
+		ok    bool
+	}{
+		{[]Kmer{{1}, {2, 3}, {2, 4}}, true},
+		{[]Kmer{{1}, {2, 3}, {2, 3}}, false},
+		{[]Kmer{{2}, {1}}, false},
+	}
+	file := filepath.Join(t.TempDir(), "kmers.dump")
+	for _, test := range tests {
+		f, err := aio.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewWriter(f)
+		for _, kmer := range test.input {
+			if err := w.Write(kmer); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadKmersList(file, true)
+		if !test.ok {
+			if err == nil {
+				t.Errorf("ReadKmersList(%v) succeeded, want error", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ReadKmersList(%v) failed: %v", test.input, err)
+		}
+		if !slices.Equal(got, test.input) {
+			t.Fatalf("ReadKmersList(%v)=%v, want %v", test.input, got, test.input)
+		}
+	}
+}
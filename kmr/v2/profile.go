@@ -98,11 +98,11 @@ type ProfileHandler struct{}
 // ProfileTuple holds a kmer and a distribution of bases around it.
 type ProfileTuple = Tuple[ProfileHandler, *ProfileData]
 
-func (h ProfileHandler) encode(p *ProfileData, w *bnry.Writer) error {
+func (h ProfileHandler) Encode(p *ProfileData, w *bnry.Writer) error {
 	return w.Write(p.P.flatten(), p.C[:])
 }
 
-func (h ProfileHandler) decode(p **ProfileData, r io.ByteReader) error {
+func (h ProfileHandler) Decode(p **ProfileData, r io.ByteReader) error {
 	var pp, c []int64
 	if err := bnry.Read(r, &pp, &c); err != nil {
 		return err
@@ -120,19 +120,19 @@ func (h ProfileHandler) decode(p **ProfileData, r io.ByteReader) error {
 	return nil
 }
 
-func (h ProfileHandler) merge(a, b *ProfileData) *ProfileData {
+func (h ProfileHandler) Merge(a, b *ProfileData) *ProfileData {
 	p := a
 	p.P.Add(&b.P)
 	p.C.Add(&b.C)
 	return p
 }
 
-func (h ProfileHandler) clone(p *ProfileData) *ProfileData {
+func (h ProfileHandler) Clone(p *ProfileData) *ProfileData {
 	pp := &ProfileData{}
 	*pp = *p
 	return pp
 }
 
-func (h ProfileHandler) new() *ProfileData {
+func (h ProfileHandler) New() *ProfileData {
 	return &ProfileData{}
 }
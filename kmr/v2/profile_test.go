@@ -111,12 +111,12 @@ func TestProfileTupleEncode(t *testing.T) {
 	buf := bytes.NewBuffer(nil)
 	bw := bnry.NewWriter(buf)
 	h := ProfileHandler{}
-	if err := h.encode(input, bw); err != nil {
+	if err := h.Encode(input, bw); err != nil {
 		t.Fatalf("Encode() failed: %v", err)
 	}
 
 	got := &ProfileData{}
-	if err := h.decode(&got, buf); err != nil {
+	if err := h.Decode(&got, buf); err != nil {
 		t.Fatalf("Decode() failed: %v", err)
 	}
 
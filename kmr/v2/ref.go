@@ -0,0 +1,117 @@
+// RefTuple logic.
+
+package kmr
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/fluhus/biostuff/sequtil"
+	"github.com/fluhus/gostuff/bnry"
+	"github.com/fluhus/kwas/util"
+)
+
+// RefTuple holds a kmer and its positions on reference sequences.
+type RefTuple = Tuple[RefHandler, RefData]
+
+type RefData struct {
+	Hits []RefHit
+}
+
+// RefHit is a position of a kmer on a reference sequence.
+type RefHit struct {
+	Contig string // Name of the reference sequence.
+	Pos    int    // 0-based start position on the forward strand.
+
+	// Whether the kmer is the reverse complement of the forward strand at
+	// this position.
+	Rev bool
+}
+
+type RefHandler struct{}
+
+func (h RefHandler) Encode(r RefData, w *bnry.Writer) error {
+	contigs := make([]string, len(r.Hits))
+	pos := make([]int, len(r.Hits))
+	rev := make([]bool, len(r.Hits))
+	for i, hit := range r.Hits {
+		contigs[i], pos[i], rev[i] = hit.Contig, hit.Pos, hit.Rev
+	}
+	return w.Write(contigs, pos, rev)
+}
+
+func (h RefHandler) Decode(r *RefData, br io.ByteReader) error {
+	var contigs []string
+	var pos []int
+	var rev []bool
+	if err := bnry.Read(br, &contigs, &pos, &rev); err != nil {
+		return err
+	}
+	if len(pos) != len(contigs) || len(rev) != len(contigs) {
+		return fmt.Errorf("mismatching hit field lengths: %d, %d, %d",
+			len(contigs), len(pos), len(rev))
+	}
+	r.Hits = r.Hits[:0]
+	for i := range contigs {
+		r.Hits = append(r.Hits, RefHit{contigs[i], pos[i], rev[i]})
+	}
+	return nil
+}
+
+func (h RefHandler) Merge(a, b RefData) RefData {
+	return RefData{append(a.Hits, b.Hits...)}
+}
+
+func (h RefHandler) Clone(r RefData) RefData {
+	return RefData{slices.Clone(r.Hits)}
+}
+
+func (h RefHandler) New() RefData {
+	return RefData{}
+}
+
+// CanonicalKmers calls fn with each canonical kmer of seq, its start position
+// and whether it is the reverse complement of the kmer in seq. Lowercase bases
+// are treated as uppercase, and kmers with characters other than ACGT are
+// skipped.
+func CanonicalKmers(seq []byte, fn func(kmer Kmer, pos int, rc bool)) {
+	if len(seq) < K {
+		return
+	}
+	upper := make([]byte, len(seq))
+	for i, b := range seq {
+		switch b {
+		case 'A', 'C', 'G', 'T':
+			upper[i] = b
+		case 'a', 'c', 'g', 't':
+			upper[i] = b - 'a' + 'A'
+		default:
+			upper[i] = 'N'
+		}
+	}
+	var buf []byte
+	util.CanonicalKmersPos(upper, K, func(kmer []byte, pos int, rc bool) {
+		buf = sequtil.DNATo2Bit(buf[:0], kmer)
+		fn(Kmer(buf), pos, rc)
+	})
+}
+
+// RefKmers calls fn with each canonical kmer of a reference sequence and its
+// position, as in CanonicalKmers.
+func RefKmers(contig string, seq []byte, fn func(Kmer, RefHit)) {
+	CanonicalKmers(seq, func(kmer Kmer, pos int, rc bool) {
+		fn(kmer, RefHit{contig, pos, rc})
+	})
+}
+
+// ContigName returns the first word of a FASTA name line, to be used as the
+// contig of its kmers.
+func ContigName(name []byte) string {
+	for i, b := range name {
+		if b == ' ' || b == '\t' {
+			return string(name[:i])
+		}
+	}
+	return string(name)
+}
@@ -0,0 +1,84 @@
+package kmr
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/fluhus/biostuff/sequtil"
+	"github.com/fluhus/gostuff/bnry"
+)
+
+func TestRefTuple_encode(t *testing.T) {
+	want := &RefTuple{Kmer: Kmer{1, 2, 3}, Data: RefData{Hits: []RefHit{
+		{"chr1", 100, false}, {"chr2", 0, true}}}}
+	buf := &bytes.Buffer{}
+	if err := want.Encode(bnry.NewWriter(buf)); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	got := &RefTuple{}
+	if err := got.Decode(buf); err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Decode()=%v, want %v", got, want)
+	}
+}
+
+func TestCanonicalKmers(t *testing.T) {
+	seq := []byte("ttttttttttggggggggggNa")
+	want := []string{"CCCCCCCCCCAAAAAAAAAA 0 true"}
+	var got []string
+	CanonicalKmers(seq, func(kmer Kmer, pos int, rc bool) {
+		got = append(got, fmt.Sprintf("%s %d %v",
+			sequtil.DNAFrom2Bit(nil, kmer[:]), pos, rc))
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("CanonicalKmers(%q)=%v, want %v", seq, got, want)
+	}
+}
+
+func TestRefKmers(t *testing.T) {
+	fwd := "AAAAAAAAAACCCCCCCCCC"
+	rev := "GGGGGGGGGGTTTTTTTTTT" // Reverse complement of fwd.
+	seq := []byte("nR" + rev + "r" + fwd[:10] + "n")
+	seq = append(seq, bytes.ToLower([]byte(fwd))...)
+	want := []string{
+		fwd + " 2 true",
+		fwd + " 34 false",
+	}
+	var got []string
+	RefKmers("x", seq, func(kmer Kmer, hit RefHit) {
+		if hit.Contig != "x" {
+			t.Errorf("RefKmers(...) contig=%q, want %q", hit.Contig, "x")
+		}
+		got = append(got, fmt.Sprintf("%s %d %v",
+			sequtil.DNAFrom2Bit(nil, kmer[:]), hit.Pos, hit.Rev))
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("RefKmers(%q)=%v, want %v", seq, got, want)
+	}
+}
+
+func TestRefKmers_short(t *testing.T) {
+	RefKmers("x", []byte("ACGT"), func(kmer Kmer, hit RefHit) {
+		t.Fatalf("RefKmers(%q) yielded %v", "ACGT", hit)
+	})
+}
+
+func TestContigName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"chr1", "chr1"},
+		{"chr1 some description", "chr1"},
+		{"chr2\tdescription", "chr2"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := ContigName([]byte(test.name)); got != test.want {
+			t.Errorf("ContigName(%q)=%q, want %q", test.name, got, test.want)
+		}
+	}
+}
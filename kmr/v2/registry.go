@@ -0,0 +1,179 @@
+// Registry of tuple types by name.
+
+package kmr
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+
+	"github.com/fluhus/gostuff/bnry"
+	"golang.org/x/exp/maps"
+)
+
+// Type-erased operations on a registered tuple type.
+type registryEntry struct {
+	// Merges sorted tuple streams into w.
+	merge func(files []string, w io.Writer) error
+
+	// Calls fn on each tuple in the file, with a function that writes it,
+	// preceded by a header record if needed.
+	forEach func(file string,
+		fn func(kmer Kmer, write func(*bnry.Writer) error) error) error
+
+	// Iterates over the tuples in the file as records.
+	records func(file string) iter.Seq2[Record, error]
+
+	// Returns an empty data instance.
+	newData func() any
+}
+
+// Record is a type-erased tuple, holding its kmer and its data.
+type Record struct {
+	Kmer Kmer
+	Data any
+}
+
+var registry = map[string]registryEntry{}
+
+func init() {
+	Register[CountHandler]("cnt")
+	Register[HasHandler]("has")
+	Register[ProfileHandler]("prf")
+	Register[BitmapHandler]("bmp")
+	Register[ClusterHandler]("cls")
+	Register[ResultHandler]("res")
+	Register[RefHandler]("ref")
+}
+
+// Register makes handler type H available by name to commands that take a
+// tuple type, such as merge and split. Packages that define their own
+// handlers should register them in an init function, and be imported by the
+// commands that use them. Panics if the name is already taken.
+func Register[H KmerDataHandler[T], T any](name string) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("tuple type %q is already registered", name))
+	}
+	registry[name] = registryEntry{
+		merge: func(files []string, w io.Writer) error {
+			m := NewMerger[H]()
+			for _, file := range files {
+				if err := m.Add(IterTuplesFile[H](file)); err != nil {
+					return err
+				}
+			}
+			return m.Dump(w)
+		},
+		forEach: func(file string,
+			fn func(Kmer, func(*bnry.Writer) error) error) error {
+			tws := map[*bnry.Writer]*TupleWriter[H, T]{}
+			var t *Tuple[H, T]
+			write := func(w *bnry.Writer) error {
+				tw := tws[w]
+				if tw == nil {
+					tw = &TupleWriter[H, T]{w: w}
+					tws[w] = tw
+				}
+				return tw.Write(t)
+			}
+			for tt, err := range IterTuplesFile[H](file) {
+				if err != nil {
+					return err
+				}
+				t = tt
+				if err := fn(t.Kmer, write); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		records: func(file string) iter.Seq2[Record, error] {
+			return func(yield func(Record, error) bool) {
+				for t, err := range IterTuplesFile[H](file) {
+					if err != nil {
+						yield(Record{}, err)
+						return
+					}
+					if !yield(Record{t.Kmer, t.Data}, nil) {
+						return
+					}
+				}
+			}
+		},
+		newData: func() any {
+			var h H
+			return h.New()
+		},
+	}
+}
+
+// Registered returns the names of the registered tuple types, sorted.
+func Registered() []string {
+	names := maps.Keys(registry)
+	slices.Sort(names)
+	return names
+}
+
+// MergeFiles merges the sorted tuples in the given files, using the handler
+// that is registered under name, and writes the result to w.
+func MergeFiles(name string, files []string, w io.Writer) error {
+	e, err := registered(name)
+	if err != nil {
+		return err
+	}
+	return e.merge(files, w)
+}
+
+// ForEachFile calls fn on each tuple in the given file, using the handler
+// that is registered under name. Fn receives the tuple's kmer and a function
+// that writes the tuple, which is valid only until fn returns. The write
+// function adds header records to each writer as needed, so each writer
+// should only be written to through it.
+func ForEachFile(name, file string,
+	fn func(kmer Kmer, write func(*bnry.Writer) error) error) error {
+	e, err := registered(name)
+	if err != nil {
+		return err
+	}
+	return e.forEach(file, fn)
+}
+
+// IterRecordsFile iterates over the tuples in the given file as records,
+// using the handler that is registered under name.
+// Record data may share memory with the next record, and should be cloned
+// using the handler if kept.
+func IterRecordsFile(name, file string) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		e, err := registered(name)
+		if err != nil {
+			yield(Record{}, err)
+			return
+		}
+		for r, err := range e.records(file) {
+			if !yield(r, err) {
+				return
+			}
+		}
+	}
+}
+
+// NewData returns an empty data instance of the handler that is registered
+// under name, as created by its New function.
+func NewData(name string) (any, error) {
+	e, err := registered(name)
+	if err != nil {
+		return nil, err
+	}
+	return e.newData(), nil
+}
+
+// Returns the registry entry of the given name.
+func registered(name string) (registryEntry, error) {
+	e, ok := registry[name]
+	if !ok {
+		return registryEntry{}, fmt.Errorf("unknown tuple type: %q, "+
+			"want one of %v", name, Registered())
+	}
+	return e, nil
+}
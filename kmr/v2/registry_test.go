@@ -0,0 +1,139 @@
+package kmr_test
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/bnry"
+	"github.com/fluhus/kwas/kmr/v2"
+)
+
+// A user-defined payload, summing quality scores.
+type qualData struct {
+	Sum float64
+}
+
+type qualHandler struct{}
+
+func (h qualHandler) Encode(q qualData, w *bnry.Writer) error {
+	return w.Write(q.Sum)
+}
+
+func (h qualHandler) Decode(q *qualData, r io.ByteReader) error {
+	return bnry.Read(r, &q.Sum)
+}
+
+func (h qualHandler) Merge(a, b qualData) qualData {
+	return qualData{a.Sum + b.Sum}
+}
+
+func (h qualHandler) Clone(q qualData) qualData {
+	return q
+}
+
+func (h qualHandler) New() qualData {
+	return qualData{}
+}
+
+type qualTuple = kmr.Tuple[qualHandler, qualData]
+
+func init() {
+	kmr.Register[qualHandler]("test-qual")
+}
+
+func TestRegister(t *testing.T) {
+	dir := t.TempDir()
+	inputs := [][]*qualTuple{
+		{
+			{Kmer: kmr.Kmer{1}, Data: qualData{1.5}},
+			{Kmer: kmr.Kmer{3}, Data: qualData{2}},
+		},
+		{
+			{Kmer: kmr.Kmer{1}, Data: qualData{0.25}},
+			{Kmer: kmr.Kmer{2}, Data: qualData{4}},
+		},
+		{
+			{Kmer: kmr.Kmer{3}, Data: qualData{0.5}},
+		},
+	}
+	var files []string
+	for i, tuples := range inputs {
+		file := filepath.Join(dir, string(rune('a'+i)))
+		files = append(files, file)
+		f, err := aio.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := bnry.NewWriter(f)
+		for _, tup := range tuples {
+			if err := tup.Encode(w); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f.Close()
+	}
+
+	if !slices.Contains(kmr.Registered(), "test-qual") {
+		t.Fatalf("Registered()=%v, want test-qual", kmr.Registered())
+	}
+
+	buf := &bytes.Buffer{}
+	if err := kmr.MergeFiles("test-qual", files, buf); err != nil {
+		t.Fatalf("MergeFiles() failed: %v", err)
+	}
+	var got []qualTuple
+	for tup, err := range kmr.IterTuplesReader[qualHandler](buf) {
+		if err != nil {
+			t.Fatalf("IterTuplesReader() failed: %v", err)
+		}
+		got = append(got, *tup)
+	}
+	want := []qualTuple{
+		{Kmer: kmr.Kmer{1}, Data: qualData{1.75}},
+		{Kmer: kmr.Kmer{2}, Data: qualData{4}},
+		{Kmer: kmr.Kmer{3}, Data: qualData{2.5}},
+	}
+	if !slices.EqualFunc(got, want, func(a, b qualTuple) bool {
+		return a.Kmer == b.Kmer && a.Data == b.Data
+	}) {
+		t.Fatalf("MergeFiles()=%v, want %v", got, want)
+	}
+
+	var kmers []kmr.Kmer
+	buf.Reset()
+	w := bnry.NewWriter(buf)
+	err := kmr.ForEachFile("test-qual", files[0],
+		func(kmer kmr.Kmer, write func(*bnry.Writer) error) error {
+			kmers = append(kmers, kmer)
+			return write(w)
+		})
+	if err != nil {
+		t.Fatalf("ForEachFile() failed: %v", err)
+	}
+	if want := []kmr.Kmer{{1}, {3}}; !slices.Equal(kmers, want) {
+		t.Fatalf("ForEachFile() kmers=%v, want %v", kmers, want)
+	}
+	n := 0
+	for range kmr.IterTuplesReader[qualHandler](buf) {
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("ForEachFile() wrote %v tuples, want 2", n)
+	}
+}
+
+func TestRegister_duplicate(t *testing.T) {
+	defer func() { recover() }()
+	kmr.Register[kmr.HasHandler]("has")
+	t.Fatalf("Register(has) succeeded, want panic")
+}
+
+func TestMergeFiles_unknown(t *testing.T) {
+	if err := kmr.MergeFiles("no-such-type", nil, io.Discard); err == nil {
+		t.Fatalf("MergeFiles(no-such-type) succeeded, want error")
+	}
+}
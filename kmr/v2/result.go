@@ -0,0 +1,170 @@
+// ResultTuple logic.
+
+package kmr
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/fluhus/biostuff/sequtil"
+	"github.com/fluhus/gostuff/bnry"
+)
+
+// ResultTuple holds a kmer and the result of its association test.
+type ResultTuple = Tuple[ResultHandler, ResultData]
+
+// ResultData is the regression result of a single variable.
+type ResultData struct {
+	N        int     // Number of samples that have the kmer.
+	RSquared float64 // Of the whole model.
+	Coef     float64
+	Pval     float64
+	CILow    float64 // 2.5% bound of the coefficient's confidence interval.
+	CIHigh   float64 // 97.5% bound of the coefficient's confidence interval.
+}
+
+type ResultHandler struct{}
+
+func (h ResultHandler) Encode(r ResultData, w *bnry.Writer) error {
+	return w.Write(r.N, r.RSquared, r.Coef, r.Pval, r.CILow, r.CIHigh)
+}
+
+func (h ResultHandler) Decode(r *ResultData, br io.ByteReader) error {
+	return bnry.Read(br, &r.N, &r.RSquared, &r.Coef, &r.Pval,
+		&r.CILow, &r.CIHigh)
+}
+
+// Merge keeps the result with the lower p-value.
+func (h ResultHandler) Merge(a, b ResultData) ResultData {
+	if b.Pval < a.Pval {
+		return b
+	}
+	return a
+}
+
+func (h ResultHandler) Clone(r ResultData) ResultData {
+	return r
+}
+
+func (h ResultHandler) New() ResultData {
+	return ResultData{}
+}
+
+// ResultCSVHeader returns the CSV columns of a result of the given variable,
+// as written by kwas.py.
+func ResultCSVHeader(col string) []string {
+	return []string{"key", "n", "rsquared", col + "_coef", col + "_pval",
+		col + "_coef_025", col + "_coef_975"}
+}
+
+// IterResultsCSV iterates over the results of the given variable in a CSV
+// written by kwas.py. Other columns are ignored.
+// Yielded tuples are in the order of the CSV, and are reused between
+// iterations.
+func IterResultsCSV(r io.Reader, col string) iter.Seq2[*ResultTuple, error] {
+	return func(yield func(*ResultTuple, error) bool) {
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("missing CSV header")
+			}
+			yield(nil, err)
+			return
+		}
+		var idx []int
+		for _, c := range ResultCSVHeader(col) {
+			i := slices.Index(header, c)
+			if i == -1 {
+				yield(nil, fmt.Errorf("did not find column %q", c))
+				return
+			}
+			idx = append(idx, i)
+		}
+
+		t := &ResultTuple{}
+		var buf []byte
+		i := 0
+		for row, err := cr.Read(); err != io.EOF; row, err = cr.Read() {
+			i++
+			if err == nil {
+				buf, err = parseResultRow(t, row, idx, buf)
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("row %d: %w", i, err))
+				return
+			}
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Parses the values of a result in a CSV row, where idx are the indexes of
+// the columns of ResultCSVHeader. Returns the 2-bit buffer for reuse.
+func parseResultRow(t *ResultTuple, row []string, idx []int,
+	buf []byte) ([]byte, error) {
+	key := row[idx[0]]
+	if len(key) != K {
+		return buf, fmt.Errorf("bad kmer length: %d, want %d", len(key), K)
+	}
+	buf = sequtil.DNATo2Bit(buf[:0], []byte(key))
+	t.Kmer = Kmer(buf)
+
+	n, err := strconv.Atoi(row[idx[1]])
+	if err != nil {
+		return buf, err
+	}
+	t.Data.N = n
+	for i, f := range []*float64{&t.Data.RSquared, &t.Data.Coef,
+		&t.Data.Pval, &t.Data.CILow, &t.Data.CIHigh} {
+		if *f, err = parseResultFloat(row[idx[i+2]]); err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// Parses a float in a CSV row. Empty fields are NaN, as pandas writes them.
+// "nan" is parsed by strconv.
+func parseResultFloat(s string) (float64, error) {
+	if s == "" {
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// WriteResultsCSV writes the given results as CSV with the columns of
+// ResultCSVHeader.
+func WriteResultsCSV(w io.Writer, col string,
+	results iter.Seq2[*ResultTuple, error]) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ResultCSVHeader(col)); err != nil {
+		return err
+	}
+	var buf []byte
+	row := make([]string, 7)
+	for t, err := range results {
+		if err != nil {
+			return err
+		}
+		buf = sequtil.DNAFrom2Bit(buf[:0], t.Kmer[:])
+		row[0] = string(buf[:K])
+		row[1] = strconv.Itoa(t.Data.N)
+		for i, f := range []float64{t.Data.RSquared, t.Data.Coef,
+			t.Data.Pval, t.Data.CILow, t.Data.CIHigh} {
+			row[i+2] = strconv.FormatFloat(f, 'g', -1, 64)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
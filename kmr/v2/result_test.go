@@ -0,0 +1,119 @@
+package kmr
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/fluhus/gostuff/bnry"
+)
+
+func TestResultTuple_encode(t *testing.T) {
+	want := &ResultTuple{Kmer: Kmer{1, 2, 3}, Data: ResultData{
+		N: 123, RSquared: 0.5, Coef: -1.25, Pval: 1e-300,
+		CILow: -2, CIHigh: -0.5}}
+	buf := &bytes.Buffer{}
+	if err := want.Encode(bnry.NewWriter(buf)); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	got := &ResultTuple{}
+	if err := got.Decode(buf); err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if got.Kmer != want.Kmer || got.Data != want.Data {
+		t.Fatalf("Decode()=%v, want %v", got, want)
+	}
+}
+
+func TestResultTuple_add(t *testing.T) {
+	a := &ResultTuple{Data: ResultData{N: 1, Pval: 0.5}}
+	b := &ResultTuple{Data: ResultData{N: 2, Pval: 0.01}}
+	a.Add(b)
+	if a.Data != b.Data {
+		t.Fatalf("Add(...)=%v, want %v", a.Data, b.Data)
+	}
+}
+
+func TestResultsCSV(t *testing.T) {
+	input := "key,n,rsquared,const_coef,kmer_coef,kmer_pval," +
+		"kmer_coef_025,kmer_coef_975\n" +
+		"AAAAAAAAAAAAAAAAAAAC,10,0.25,1,0.5,0.001,0.25,0.75\n" +
+		"TTTTTTTTTTTTTTTTTTTT,3,0.125,2,-3,1e-20,-4,-2\n"
+	want := []ResultTuple{
+		{Kmer: Kmer{0, 0, 0, 0, 1}, Data: ResultData{
+			10, 0.25, 0.5, 0.001, 0.25, 0.75}},
+		{Kmer: Kmer{255, 255, 255, 255, 255}, Data: ResultData{
+			3, 0.125, -3, 1e-20, -4, -2}},
+	}
+	var got []ResultTuple
+	for r, err := range IterResultsCSV(strings.NewReader(input), "kmer") {
+		if err != nil {
+			t.Fatalf("IterResultsCSV() failed: %v", err)
+		}
+		got = append(got, *r)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("IterResultsCSV()=%v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i].Kmer != want[i].Kmer || got[i].Data != want[i].Data {
+			t.Fatalf("IterResultsCSV()=%v, want %v", got, want)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	err := WriteResultsCSV(out, "kmer",
+		IterResultsCSV(strings.NewReader(input), "kmer"))
+	if err != nil {
+		t.Fatalf("WriteResultsCSV() failed: %v", err)
+	}
+	wantCSV := "key,n,rsquared,kmer_coef,kmer_pval,kmer_coef_025,kmer_coef_975\n" +
+		"AAAAAAAAAAAAAAAAAAAC,10,0.25,0.5,0.001,0.25,0.75\n" +
+		"TTTTTTTTTTTTTTTTTTTT,3,0.125,-3,1e-20,-4,-2\n"
+	if out.String() != wantCSV {
+		t.Fatalf("WriteResultsCSV()=%q, want %q", out.String(), wantCSV)
+	}
+}
+
+func TestResultsCSV_nan(t *testing.T) {
+	input := "key,n,rsquared,kmer_coef,kmer_pval,kmer_coef_025,kmer_coef_975\n" +
+		"AAAAAAAAAAAAAAAAAAAC,10,,nan,NaN,,0.75\n"
+	n := 0
+	for r, err := range IterResultsCSV(strings.NewReader(input), "kmer") {
+		if err != nil {
+			t.Fatalf("IterResultsCSV() failed: %v", err)
+		}
+		d := r.Data
+		if d.N != 10 || !math.IsNaN(d.RSquared) || !math.IsNaN(d.Coef) ||
+			!math.IsNaN(d.Pval) || !math.IsNaN(d.CILow) || d.CIHigh != 0.75 {
+			t.Fatalf("IterResultsCSV()=%v, want NaNs", d)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("IterResultsCSV() returned %d results, want 1", n)
+	}
+}
+
+func TestResultsCSV_bad(t *testing.T) {
+	inputs := []string{
+		"",
+		"key,n,rsquared\n",
+		"key,n,rsquared,kmer_coef,kmer_pval,kmer_coef_025,kmer_coef_975\n" +
+			"AAAA,10,0.25,0.5,0.001,0.25,0.75\n",
+		"key,n,rsquared,kmer_coef,kmer_pval,kmer_coef_025,kmer_coef_975\n" +
+			"AAAAAAAAAAAAAAAAAAAC,10,0.25,x,0.001,0.25,0.75\n",
+	}
+	for _, input := range inputs {
+		var err error
+		for _, err = range IterResultsCSV(strings.NewReader(input), "kmer") {
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			t.Errorf("IterResultsCSV(%q) succeeded, want error", input)
+		}
+	}
+}
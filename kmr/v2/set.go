@@ -0,0 +1,131 @@
+// Set operations on sorted kmer streams.
+
+package kmr
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/fluhus/kwas/iterx"
+)
+
+// Occurrences iterates over the distinct kmers of the given streams, with a
+// mask of the streams that have each one.
+// Streams should be sorted with no duplicates, like dump files of sorted
+// kmers; otherwise an error is yielded.
+// Holds one kmer per stream in memory. The yielded mask is reused between
+// iterations.
+func Occurrences(streams []iter.Seq2[Kmer, error],
+) iter.Seq2[KmerMask, error] {
+	return func(yield func(KmerMask, error) bool) {
+		its := make([]*iterx.Iter[Kmer], len(streams))
+		for i, s := range streams {
+			its[i] = iterx.New(checkSorted(s, identity,
+				fmt.Sprintf("stream #%d", i)))
+			defer its[i].Stop()
+		}
+		heads := make([]Kmer, len(streams))
+		ok := make([]bool, len(streams))
+		advance := func(i int) error {
+			var err error
+			heads[i], err, ok[i] = its[i].Next()
+			return err
+		}
+		for i := range its {
+			if err := advance(i); err != nil {
+				yield(KmerMask{}, err)
+				return
+			}
+		}
+
+		m := KmerMask{Mask: make([]bool, len(streams))}
+		for {
+			found := false
+			for i := range heads {
+				if ok[i] && (!found || heads[i].Less(m.Kmer)) {
+					m.Kmer = heads[i]
+					found = true
+				}
+			}
+			if !found { // All streams are done.
+				return
+			}
+			m.N = 0
+			for i := range heads {
+				m.Mask[i] = ok[i] && heads[i] == m.Kmer
+				if !m.Mask[i] {
+					continue
+				}
+				m.N++
+				if err := advance(i); err != nil {
+					yield(KmerMask{}, err)
+					return
+				}
+			}
+			if !yield(m, nil) {
+				return
+			}
+		}
+	}
+}
+
+// KmerMask is a kmer with the streams that have it.
+type KmerMask struct {
+	Kmer Kmer
+	Mask []bool // Whether each stream has the kmer.
+	N    int    // Number of streams that have the kmer.
+}
+
+// Union iterates over the kmers that are in any of the given sorted streams.
+func Union(streams ...iter.Seq2[Kmer, error]) iter.Seq2[Kmer, error] {
+	return filterMasks(streams, func(m KmerMask) bool { return true })
+}
+
+// Intersection iterates over the kmers that are in all of the given sorted
+// streams.
+func Intersection(streams ...iter.Seq2[Kmer, error]) iter.Seq2[Kmer, error] {
+	return AtLeast(len(streams), streams...)
+}
+
+// Difference iterates over the kmers of the first sorted stream that are
+// not in any of the others.
+func Difference(streams ...iter.Seq2[Kmer, error]) iter.Seq2[Kmer, error] {
+	return filterMasks(streams, func(m KmerMask) bool {
+		return m.Mask[0] && m.N == 1
+	})
+}
+
+// SymmetricDifference iterates over the kmers that are in an odd number of
+// the given sorted streams, which is the result of applying symmetric
+// difference to the streams one after the other.
+func SymmetricDifference(streams ...iter.Seq2[Kmer, error],
+) iter.Seq2[Kmer, error] {
+	return filterMasks(streams, func(m KmerMask) bool { return m.N%2 == 1 })
+}
+
+// AtLeast iterates over the kmers that are in at least n of the given sorted
+// streams.
+func AtLeast(n int, streams ...iter.Seq2[Kmer, error]) iter.Seq2[Kmer, error] {
+	return filterMasks(streams, func(m KmerMask) bool { return m.N >= n })
+}
+
+// Yields the kmers of the given streams whose masks pass keep.
+func filterMasks(streams []iter.Seq2[Kmer, error], keep func(KmerMask) bool,
+) iter.Seq2[Kmer, error] {
+	return func(yield func(Kmer, error) bool) {
+		for m, err := range Occurrences(streams) {
+			if err != nil {
+				yield(Kmer{}, err)
+				return
+			}
+			if keep(m) && !yield(m.Kmer, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Returns its input.
+func identity[T any](t T) T {
+	return t
+}
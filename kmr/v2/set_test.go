@@ -0,0 +1,100 @@
+package kmr
+
+import (
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestSetOps(t *testing.T) {
+	a := []Kmer{{1}, {2}, {3}, {5}}
+	b := []Kmer{{2}, {3}, {4}}
+	c := []Kmer{{3}, {5}, {6}}
+	tests := []struct {
+		name string
+		op   func(...iter.Seq2[Kmer, error]) iter.Seq2[Kmer, error]
+		want []Kmer
+	}{
+		{"Union", Union, []Kmer{{1}, {2}, {3}, {4}, {5}, {6}}},
+		{"Intersection", Intersection, []Kmer{{3}}},
+		{"Difference", Difference, []Kmer{{1}}},
+		{"SymmetricDifference", SymmetricDifference,
+			[]Kmer{{1}, {3}, {4}, {6}}},
+		{"AtLeast(2)", func(s ...iter.Seq2[Kmer, error]) iter.Seq2[Kmer, error] {
+			return AtLeast(2, s...)
+		}, []Kmer{{2}, {3}, {5}}},
+	}
+	for _, test := range tests {
+		got, err := collectKmers(test.op(
+			kmerSeq(a), kmerSeq(b), kmerSeq(c)))
+		if err != nil {
+			t.Fatalf("%s failed: %v", test.name, err)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s=%v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	a := []Kmer{{1}, {3}}
+	b := []Kmer{{2}, {3}}
+	want := []struct {
+		kmer Kmer
+		mask []bool
+		n    int
+	}{
+		{Kmer{1}, []bool{true, false}, 1},
+		{Kmer{2}, []bool{false, true}, 1},
+		{Kmer{3}, []bool{true, true}, 2},
+	}
+	i := 0
+	for m, err := range Occurrences(
+		[]iter.Seq2[Kmer, error]{kmerSeq(a), kmerSeq(b)}) {
+		if err != nil {
+			t.Fatalf("Occurrences() failed: %v", err)
+		}
+		if i >= len(want) {
+			t.Fatalf("Occurrences() yielded more than %d kmers", len(want))
+		}
+		if m.Kmer != want[i].kmer || !slices.Equal(m.Mask, want[i].mask) ||
+			m.N != want[i].n {
+			t.Errorf("Occurrences()[%d]=%v, want %v", i, m, want[i])
+		}
+		i++
+	}
+	if i != len(want) {
+		t.Fatalf("Occurrences() yielded %d kmers, want %d", i, len(want))
+	}
+}
+
+func TestSetOps_unsorted(t *testing.T) {
+	sorted := []Kmer{{1}, {2}}
+	for _, bad := range [][]Kmer{{{2}, {1}}, {{1}, {1}}} {
+		_, err := collectKmers(Union(kmerSeq(sorted), kmerSeq(bad)))
+		if err == nil {
+			t.Errorf("Union(%v, %v) succeeded, want error", sorted, bad)
+		}
+	}
+}
+
+func kmerSeq(kmers []Kmer) iter.Seq2[Kmer, error] {
+	return func(yield func(Kmer, error) bool) {
+		for _, k := range kmers {
+			if !yield(k, nil) {
+				return
+			}
+		}
+	}
+}
+
+func collectKmers(seq iter.Seq2[Kmer, error]) ([]Kmer, error) {
+	var result []Kmer
+	for k, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, k)
+	}
+	return result, nil
+}
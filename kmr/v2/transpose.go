@@ -0,0 +1,405 @@
+// Sample-major (transposed) HAS logic.
+
+package kmr
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"slices"
+
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/bnry"
+	"golang.org/x/exp/maps"
+)
+
+// Key of the header record of a sample-major file, whose only value is the
+// number of samples of the HAS file it was transposed from.
+const sampleHeaderKey = -1
+
+// SampleKmers holds a sample index and the ordinals of the kmers it has,
+// in the order of the HAS file it was transposed from.
+type SampleKmers struct {
+	Sample int
+	Kmers  []int
+
+	// Number of samples of the HAS file, if it had one. Read from the
+	// stream's header record.
+	NSamples int
+}
+
+// Encode writes the sample and its delta-encoded kmer ordinals.
+// Kmers should be sorted.
+func (s *SampleKmers) Encode(w *bnry.Writer) error {
+	return encodeRecord(w, s.Sample, s.Kmers)
+}
+
+// Decode reads a sample and its kmer ordinals into this instance.
+// A header record is skipped, and its number of samples is kept.
+func (s *SampleKmers) Decode(r io.ByteReader) error {
+	for {
+		var err error
+		s.Sample, s.Kmers, err = decodeRecord(r, s.Kmers)
+		if err != nil {
+			return err
+		}
+		if s.Sample != sampleHeaderKey {
+			return nil
+		}
+		if len(s.Kmers) != 1 || s.Kmers[0] < 0 {
+			return fmt.Errorf("bad sample-major header: %v", s.Kmers)
+		}
+		s.NSamples = s.Kmers[0]
+	}
+}
+
+// IterSampleKmersFile iterates over the samples in a sample-major file.
+// The yielded instance is reused between iterations.
+func IterSampleKmersFile(file string) iter.Seq2[*SampleKmers, error] {
+	return func(yield func(*SampleKmers, error) bool) {
+		f, err := aio.Open(file)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer f.Close()
+		for s, err := range IterSampleKmersReader(f) {
+			if !yield(s, err) {
+				return
+			}
+		}
+	}
+}
+
+// IterSampleKmersReader iterates over the samples in a sample-major stream.
+// The yielded instance is reused between iterations.
+func IterSampleKmersReader(r io.ByteReader) iter.Seq2[*SampleKmers, error] {
+	return func(yield func(*SampleKmers, error) bool) {
+		s := &SampleKmers{}
+		var err error
+		for err = s.Decode(r); err == nil; err = s.Decode(r) {
+			if !yield(s, nil) {
+				return
+			}
+		}
+		if err != io.EOF {
+			yield(nil, err)
+		}
+	}
+}
+
+// TransposeHAS writes the sample-major form of the given HAS tuples to w,
+// and their kmers in dump format to kw. If the tuples have a number of
+// samples, w starts with a header record that holds it.
+// Holds up to bufSize sample-kmer pairs in memory, and spills the rest to
+// temporary files in tmpDir.
+func TransposeHAS(has iter.Seq2[*HasTuple, error], w, kw io.Writer,
+	bufSize int, tmpDir string) error {
+	kmers := NewWriter(kw)
+	nsamples := 0
+	rows := func(yield func(record, error) bool) {
+		i := 0
+		for t, err := range has {
+			if err == nil {
+				err = kmers.Write(t.Kmer)
+			}
+			if err == nil {
+				nsamples = mergeNSamples(nsamples, t.Data.NSamples)
+				err = checkNSamples(nsamples)
+			}
+			if err != nil {
+				yield(record{}, err)
+				return
+			}
+			if !yield(record{i, t.Data.Samples}, nil) {
+				return
+			}
+			i++
+		}
+	}
+	bw := bnry.NewWriter(w)
+	wroteHeader := false
+	writeHeader := func() error {
+		if wroteHeader || nsamples == 0 {
+			return nil
+		}
+		wroteHeader = true
+		return encodeRecord(bw, sampleHeaderKey, []int{nsamples})
+	}
+	err := transpose(rows, bufSize, tmpDir, func(r record) error {
+		if err := writeHeader(); err != nil {
+			return err
+		}
+		return encodeRecord(bw, r.key, r.vals)
+	})
+	if err != nil {
+		return err
+	}
+	return writeHeader()
+}
+
+// UntransposeHAS writes the HAS tuples of the given sample-major records to
+// w, with the number of samples of their header if they had one.
+// Kmers should be the kmers that were written by TransposeHAS.
+// Holds up to bufSize sample-kmer pairs in memory, and spills the rest to
+// temporary files in tmpDir.
+func UntransposeHAS(samples iter.Seq2[*SampleKmers, error],
+	kmers iter.Seq2[Kmer, error], w io.Writer,
+	bufSize int, tmpDir string) error {
+	nsamples := 0
+	rows := func(yield func(record, error) bool) {
+		for s, err := range samples {
+			if err != nil {
+				yield(record{}, err)
+				return
+			}
+			nsamples = s.NSamples
+			if !yield(record{s.Sample, s.Kmers}, nil) {
+				return
+			}
+		}
+	}
+
+	tw := NewTupleWriter[HasHandler](w)
+	nextKmer, stop := iter.Pull2(kmers)
+	defer stop()
+	i := 0
+	t := &HasTuple{}
+	// Writes kmers up to ordinal n (exclusive) with the given samples on the
+	// last one.
+	writeUntil := func(n int, samples []int) error {
+		for ; i < n; i++ {
+			kmer, err, ok := nextKmer()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("kmer ordinal %d is out of range, "+
+					"found %d kmers", n-1, i)
+			}
+			t.Kmer = kmer
+			t.Data.Samples = nil
+			t.Data.NSamples = nsamples
+			if i == n-1 {
+				t.Data.Samples = samples
+			}
+			if err := tw.Write(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := transpose(rows, bufSize, tmpDir, func(r record) error {
+		return writeUntil(r.key+1, r.vals)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Remaining kmers with no samples.
+	for {
+		kmer, err, ok := nextKmer()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		t.Kmer = kmer
+		t.Data.Samples = nil
+		t.Data.NSamples = nsamples
+		if err := tw.Write(t); err != nil {
+			return err
+		}
+	}
+}
+
+// A key with a sorted list of values.
+type record struct {
+	key  int
+	vals []int
+}
+
+// Writes a key and its delta-encoded values.
+func encodeRecord(w *bnry.Writer, key int, vals []int) error {
+	toDiffs(vals)
+	err := w.Write(key, vals)
+	fromDiffs(vals)
+	return err
+}
+
+// Reads a key and its values, reusing buf for the values.
+func decodeRecord(r io.ByteReader, buf []int) (int, []int, error) {
+	key := 0
+	vals := buf[:0]
+	if err := bnry.Read(r, &key, &vals); err != nil {
+		return 0, buf, err
+	}
+	fromDiffs(vals)
+	return key, vals, nil
+}
+
+// Transposes a stream of records with increasing keys, so that each value
+// becomes a key and the keys become its values.
+// Calls out with the output records by increasing key.
+// Holds up to bufSize values in memory, and spills the rest to temporary
+// files in tmpDir.
+func transpose(in iter.Seq2[record, error], bufSize int, tmpDir string,
+	out func(record) error) error {
+	if bufSize < 1 {
+		return fmt.Errorf("bad buffer size: %d", bufSize)
+	}
+
+	var runs []string
+	defer func() {
+		for _, f := range runs {
+			os.Remove(f)
+		}
+	}()
+
+	m := map[int][]int{}
+	n := 0
+	last := -1
+	for r, err := range in {
+		if err != nil {
+			return err
+		}
+		if r.key <= last {
+			return fmt.Errorf("keys are not increasing: %d after %d",
+				r.key, last)
+		}
+		last = r.key
+		for _, v := range r.vals {
+			m[v] = append(m[v], r.key)
+		}
+		n += len(r.vals)
+		if n >= bufSize {
+			run, err := writeRun(m, tmpDir)
+			if run != "" {
+				runs = append(runs, run)
+			}
+			if err != nil {
+				return err
+			}
+			m = map[int][]int{}
+			n = 0
+		}
+	}
+
+	if len(runs) == 0 { // Everything fit in memory.
+		for _, k := range sortedKeys(m) {
+			if err := out(record{k, m[k]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(m) > 0 {
+		run, err := writeRun(m, tmpDir)
+		if run != "" {
+			runs = append(runs, run)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return mergeRuns(runs, out)
+}
+
+// Writes the records in m to a temporary file, sorted by key.
+// Returns the file's name.
+func writeRun(m map[int][]int, tmpDir string) (string, error) {
+	f, err := os.CreateTemp(tmpDir, "kmr-transpose-*")
+	if err != nil {
+		return "", err
+	}
+	bf := bufio.NewWriter(f)
+	w := bnry.NewWriter(bf)
+	for _, k := range sortedKeys(m) {
+		if err := encodeRecord(w, k, m[k]); err != nil {
+			f.Close()
+			return f.Name(), err
+		}
+	}
+	if err := bf.Flush(); err != nil {
+		f.Close()
+		return f.Name(), err
+	}
+	return f.Name(), f.Close()
+}
+
+// Merges run files by key. Values of equal keys are concatenated by the
+// order of the runs.
+func mergeRuns(runs []string, out func(record) error) error {
+	type run struct {
+		next func() (record, error, bool)
+		head record
+		ok   bool
+	}
+	var rs []*run
+	for _, file := range runs {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r := bufio.NewReader(f)
+		next, stop := iter.Pull2(func(yield func(record, error) bool) {
+			for {
+				k, v, err := decodeRecord(r, nil)
+				if err == io.EOF {
+					return
+				}
+				if !yield(record{k, v}, err) || err != nil {
+					return
+				}
+			}
+		})
+		defer stop()
+		rs = append(rs, &run{next: next})
+	}
+	advance := func(r *run) error {
+		var err error
+		r.head, err, r.ok = r.next()
+		return err
+	}
+	for _, r := range rs {
+		if err := advance(r); err != nil {
+			return err
+		}
+	}
+
+	for {
+		key := -1
+		for _, r := range rs {
+			if r.ok && (key == -1 || r.head.key < key) {
+				key = r.head.key
+			}
+		}
+		if key == -1 { // All runs are done.
+			return nil
+		}
+		var vals []int
+		for _, r := range rs {
+			if r.ok && r.head.key == key {
+				vals = append(vals, r.head.vals...)
+				if err := advance(r); err != nil {
+					return err
+				}
+			}
+		}
+		if err := out(record{key, vals}); err != nil {
+			return err
+		}
+	}
+}
+
+// Returns the keys of m in ascending order.
+func sortedKeys[V any](m map[int]V) []int {
+	keys := maps.Keys(m)
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,149 @@
+package kmr
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/fluhus/gostuff/bnry"
+)
+
+func TestTransposeHAS(t *testing.T) {
+	input := []*HasTuple{
+		{Kmer: Kmer{1}, Data: HasData{Samples: []int{0, 2, 5}}},
+		{Kmer: Kmer{2}, Data: HasData{Samples: []int{}}},
+		{Kmer: Kmer{3}, Data: HasData{Samples: []int{2}}},
+		{Kmer: Kmer{4}, Data: HasData{Samples: []int{1, 2, 3, 5}}},
+		{Kmer: Kmer{5}, Data: HasData{Samples: []int{5}}},
+		{Kmer: Kmer{6}, Data: HasData{Samples: []int{}}},
+	}
+	want := []SampleKmers{
+		{Sample: 0, Kmers: []int{0}},
+		{Sample: 1, Kmers: []int{3}},
+		{Sample: 2, Kmers: []int{0, 2, 3}},
+		{Sample: 3, Kmers: []int{3}},
+		{Sample: 5, Kmers: []int{0, 3, 4}},
+	}
+	for _, bufSize := range []int{1, 2, 3, 100} {
+		smp, kmers := &bytes.Buffer{}, &bytes.Buffer{}
+		err := TransposeHAS(hasSeq(input), smp, kmers, bufSize, t.TempDir())
+		if err != nil {
+			t.Fatalf("TransposeHAS(bufSize=%v) failed: %v", bufSize, err)
+		}
+
+		var got []SampleKmers
+		for s, err := range IterSampleKmersReader(bytes.NewReader(smp.Bytes())) {
+			if err != nil {
+				t.Fatalf("IterSampleKmersReader() failed: %v", err)
+			}
+			got = append(got, SampleKmers{Sample: s.Sample,
+				Kmers: append([]int{}, s.Kmers...)})
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("TransposeHAS(bufSize=%v)=%v, want %v", bufSize, got, want)
+		}
+
+		has := &bytes.Buffer{}
+		err = UntransposeHAS(IterSampleKmersReader(smp),
+			IterKmersReader(kmers), has, bufSize, t.TempDir())
+		if err != nil {
+			t.Fatalf("UntransposeHAS(bufSize=%v) failed: %v", bufSize, err)
+		}
+		var gotHAS []*HasTuple
+		for tup, err := range IterTuplesReader[HasHandler](has) {
+			if err != nil {
+				t.Fatalf("IterTuplesReader() failed: %v", err)
+			}
+			gotHAS = append(gotHAS, tup.Clone())
+		}
+		if len(gotHAS) != len(input) {
+			t.Fatalf("UntransposeHAS(bufSize=%v) returned %v tuples, want %v",
+				bufSize, len(gotHAS), len(input))
+		}
+		for i := range input {
+			if gotHAS[i].Kmer != input[i].Kmer || !reflect.DeepEqual(
+				append([]int{}, gotHAS[i].Data.Samples...),
+				input[i].Data.Samples) {
+				t.Fatalf("UntransposeHAS(bufSize=%v)[%v]=%v, want %v",
+					bufSize, i, gotHAS[i], input[i])
+			}
+		}
+	}
+}
+
+func TestTransposeHAS_nsamples(t *testing.T) {
+	input := []*HasTuple{
+		{Kmer: Kmer{1}, Data: HasData{Samples: []int{0, 1, 2, 4}, NSamples: 6}},
+		{Kmer: Kmer{2}, Data: HasData{Samples: []int{3}, NSamples: 6}},
+	}
+	smp, kmers := &bytes.Buffer{}, &bytes.Buffer{}
+	err := TransposeHAS(hasSeq(input), smp, kmers, 100, t.TempDir())
+	if err != nil {
+		t.Fatalf("TransposeHAS(...) failed: %v", err)
+	}
+	has := &bytes.Buffer{}
+	err = UntransposeHAS(IterSampleKmersReader(smp),
+		IterKmersReader(kmers), has, 100, t.TempDir())
+	if err != nil {
+		t.Fatalf("UntransposeHAS(...) failed: %v", err)
+	}
+	i := 0
+	for tup, err := range IterTuplesReader[HasHandler](has) {
+		if err != nil {
+			t.Fatalf("IterTuplesReader() failed: %v", err)
+		}
+		if !hasTuplesEqual(tup, input[i]) ||
+			tup.Data.NSamples != input[i].Data.NSamples {
+			t.Fatalf("UntransposeHAS(...)[%v]=%v, want %v", i, tup, input[i])
+		}
+		i++
+	}
+	if i != len(input) {
+		t.Fatalf("UntransposeHAS(...) returned %v tuples, want %v",
+			i, len(input))
+	}
+
+	input[1].Data.NSamples = 7
+	err = TransposeHAS(hasSeq(input), &bytes.Buffer{}, &bytes.Buffer{},
+		100, t.TempDir())
+	if err == nil {
+		t.Fatalf("TransposeHAS(%v, %v samples) succeeded, want error",
+			input[0].Data.NSamples, input[1].Data.NSamples)
+	}
+}
+
+func TestTransposeHAS_badSize(t *testing.T) {
+	err := TransposeHAS(hasSeq(nil), &bytes.Buffer{}, &bytes.Buffer{},
+		0, t.TempDir())
+	if err == nil {
+		t.Fatalf("TransposeHAS(bufSize=0) succeeded, want error")
+	}
+}
+
+func TestSampleKmers_encode(t *testing.T) {
+	want := &SampleKmers{Sample: 12, Kmers: []int{3, 10, 11, 500}}
+	buf := &bytes.Buffer{}
+	if err := want.Encode(bnry.NewWriter(buf)); err != nil {
+		t.Fatalf("%v.Encode() failed: %v", want, err)
+	}
+	if !reflect.DeepEqual(want.Kmers, []int{3, 10, 11, 500}) {
+		t.Fatalf("Encode() changed receiver: %v", want)
+	}
+	got := &SampleKmers{}
+	if err := got.Decode(buf); err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Decode()=%v, want %v", got, want)
+	}
+}
+
+func hasSeq(tuples []*HasTuple) func(yield func(*HasTuple, error) bool) {
+	return func(yield func(*HasTuple, error) bool) {
+		for _, t := range tuples {
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
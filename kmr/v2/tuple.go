@@ -3,6 +3,7 @@
 package kmr
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
@@ -18,45 +19,63 @@ type Tuple[H KmerDataHandler[T], T any] struct {
 }
 
 // KmerDataHandler implements functions for handling data in a kmer tuple.
+// Other packages can implement it to attach their own data to kmers, and use
+// Register to make it available to the merge and split commands.
 type KmerDataHandler[T any] interface {
-	encode(T, *bnry.Writer) error   // Writes the data.
-	decode(*T, io.ByteReader) error // Loads data into this object.
-	merge(T, T) T                   // Merges two pieces of data.
-	clone(T) T                      // Deep-copies the data.
-	new() T                         // Initializes an empty data.
+	Encode(T, *bnry.Writer) error   // Writes the data.
+	Decode(*T, io.ByteReader) error // Loads data into this object.
+	Merge(T, T) T                   // Merges two pieces of data.
+	Clone(T) T                      // Deep-copies the data.
+	New() T                         // Initializes an empty data.
 }
 
+// headerHandler is implemented by handlers whose data holds a value that is
+// shared by the tuples of a stream, such as the number of samples. That value
+// is written once in a header record, rather than in each tuple.
+type headerHandler[T any] interface {
+	header(T) int                         // Returns the shared value.
+	encodeHeader(int, *bnry.Writer) error // Writes the data of a header record.
+}
+
+// Returned by the Decode of a headerHandler after reading a header record.
+// The handler keeps the header's value in the data it decodes into.
+var errHeader = errors.New("header record")
+
 // Encode writes this kmer and its data to the writer.
 func (t *Tuple[H, T]) Encode(w *bnry.Writer) error {
 	t.buf = t.Kmer[:]
 	if err := w.Write(t.buf); err != nil {
 		return err
 	}
-	if err := t.h.encode(t.Data, w); err != nil {
+	if err := t.h.Encode(t.Data, w); err != nil {
 		return err
 	}
 	return nil
 }
 
 // Decode reads a kmer and its data and writes it to this instance.
+// Header records are skipped, and their values are kept in the data.
 func (t *Tuple[H, T]) Decode(r io.ByteReader) error {
-	t.buf = t.Kmer[:0]
-	if err := bnry.Read(r, &t.buf); err != nil {
+	for {
+		t.buf = t.Kmer[:0]
+		if err := bnry.Read(r, &t.buf); err != nil {
+			return err
+		}
+		if len(t.buf) != len(t.Kmer) {
+			return fmt.Errorf("bad kmer length: %v, want %v",
+				len(t.buf), len(t.Kmer))
+		}
+		err := t.h.Decode(&t.Data, r)
+		if err == errHeader {
+			continue
+		}
 		return err
 	}
-	if len(t.buf) != len(t.Kmer) {
-		return fmt.Errorf("bad kmer length: %v, want %v",
-			len(t.buf), len(t.Kmer))
-	}
-	if err := t.h.decode(&t.Data, r); err != nil {
-		return err
-	}
-	return nil
 }
 
 // Clone returns a deep copy of this instance.
 func (t *Tuple[H, T]) Clone() *Tuple[H, T] {
-	return &Tuple[H, T]{Kmer: t.Kmer, Data: t.h.clone(t.Data), buf: nil}
+	return &Tuple[H, T]{Kmer: t.Kmer, Data: t.h.Clone(t.Data), buf: nil}
 }
 
 // Add adds the data of another kmer to this one.
@@ -64,11 +83,42 @@ func (t *Tuple[H, T]) Add(other *Tuple[H, T]) {
 	if t.Kmer != other.Kmer {
 		panic(fmt.Sprintf("mismatching kmers: %v %v", t.Kmer, other.Kmer))
 	}
-	t.Data = t.h.merge(t.Data, other.Data)
+	t.Data = t.h.Merge(t.Data, other.Data)
 }
 
 func NewTuple[H KmerDataHandler[T], T any]() *Tuple[H, T] {
 	t := &Tuple[H, T]{}
-	t.Data = t.h.new()
+	t.Data = t.h.New()
 	return t
 }
+
+// TupleWriter writes tuples to a stream. For tuple types with a value that is
+// shared by the stream, such as the number of samples of HAS tuples, it
+// writes a header record whenever that value changes.
+type TupleWriter[H KmerDataHandler[T], T any] struct {
+	w      *bnry.Writer
+	header int // Last written header value.
+}
+
+// NewTupleWriter returns a tuple writer that writes to the given writer.
+func NewTupleWriter[H KmerDataHandler[T], T any](
+	w io.Writer) *TupleWriter[H, T] {
+	return &TupleWriter[H, T]{w: bnry.NewWriter(w)}
+}
+
+// Write writes the given tuple, preceded by a header record if needed.
+func (w *TupleWriter[H, T]) Write(t *Tuple[H, T]) error {
+	if h, ok := any(t.h).(headerHandler[T]); ok {
+		if v := h.header(t.Data); v != w.header {
+			var zero Kmer
+			if err := w.w.Write(zero[:]); err != nil {
+				return err
+			}
+			if err := h.encodeHeader(v, w.w); err != nil {
+				return err
+			}
+			w.header = v
+		}
+	}
+	return t.Encode(w.w)
+}
@@ -2,9 +2,9 @@ package kmr
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"math/rand"
-	"sort"
 	"testing"
 
 	"github.com/fluhus/gostuff/bnry"
@@ -90,6 +90,273 @@ func TestHasTuple_encode(t *testing.T) {
 	}
 }
 
+func TestHasTuple_encodeComplement(t *testing.T) {
+	all := make([]int, 100)
+	for i := range all {
+		all[i] = i
+	}
+	without := func(a []int, x int) []int {
+		return slices.Delete(slices.Clone(a), x, x+1)
+	}
+	shuffled := slices.Clone(all)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	tests := []struct {
+		samples  []int
+		nsamples int
+		comp     bool // Expecting complement encoding.
+	}{
+		{without(all, 30), 100, true},
+		{all, 100, true},
+		{without(shuffled, 30), 100, true},
+		{[]int{1, 3, 5}, 100, false},
+		{without(all, 30), 0, false},
+		{[]int{0, 1, 2, 3, 4, 5, 6, 8, 9}, 10, false}, // Marker is too long.
+	}
+	for _, test := range tests {
+		tup := &HasTuple{Kmer: Kmer{1, 2}, Data: HasData{
+			Samples: slices.Clone(test.samples), NSamples: test.nsamples}}
+		buf := &bytes.Buffer{}
+		if err := NewTupleWriter[HasHandler](buf).Write(tup); err != nil {
+			t.Fatalf("Write(%v) failed: %v", test.samples, err)
+		}
+		if !slices.Equal(tup.Data.Samples, test.samples) {
+			t.Fatalf("Write(%v) changed samples to %v",
+				test.samples, tup.Data.Samples)
+		}
+		single := &bytes.Buffer{}
+		if err := tup.Encode(bnry.NewWriter(single)); err != nil {
+			t.Fatalf("Encode(%v) failed: %v", test.samples, err)
+		}
+		marker, _ := binary.ReadUvarint(
+			bytes.NewReader(single.Bytes()[1+K2B:]))
+		if comp := marker == complementMarker; comp != test.comp {
+			t.Fatalf("Encode(%v) complement=%v, want %v",
+				test.samples, comp, test.comp)
+		}
+		got := &HasTuple{}
+		if err := got.Decode(buf); err != nil {
+			t.Fatalf("Decode(%v) failed: %v", test.samples, err)
+		}
+		if got.Data.NSamples != test.nsamples {
+			t.Fatalf("Decode(%v).NSamples=%v, want %v",
+				test.samples, got.Data.NSamples, test.nsamples)
+		}
+		want := snm.Sorted(slices.Clone(test.samples))
+		if !slices.Equal(got.Data.Samples, want) {
+			t.Fatalf("Decode(Write(%v))=%v, want %v",
+				test.samples, got.Data.Samples, want)
+		}
+	}
+}
+
+func TestHasTuple_headerOnce(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewTupleWriter[HasHandler](buf)
+	for i := range 3 {
+		tup := &HasTuple{Kmer: Kmer{byte(i)},
+			Data: HasData{Samples: rand.Perm(100)[:90], NSamples: 100,
+				SortOnEncode: true}}
+		if err := w.Write(tup); err != nil {
+			t.Fatalf("Write(%v) failed: %v", tup, err)
+		}
+	}
+	var tups, headers int
+	for r := bytes.NewReader(buf.Bytes()); r.Len() > 0; {
+		var kmer []byte
+		if err := bnry.Read(r, &kmer); err != nil {
+			t.Fatal(err)
+		}
+		marker, _ := binary.ReadUvarint(r)
+		switch marker {
+		case headerMarker:
+			headers++
+			binary.ReadUvarint(r)
+		case complementMarker:
+			tups++
+			var absent []int
+			bnry.Read(r, &absent)
+		default:
+			t.Fatalf("found marker %d, want a header or complement", marker)
+		}
+	}
+	if headers != 1 || tups != 3 {
+		t.Fatalf("Write(...) wrote %d headers and %d tuples, want 1 and 3",
+			headers, tups)
+	}
+}
+
+func TestHasTuple_decodeComplementNoHeader(t *testing.T) {
+	tup := &HasTuple{Data: HasData{Samples: rand.Perm(100)[:90],
+		NSamples: 100, SortOnEncode: true}}
+	buf := &bytes.Buffer{}
+	if err := tup.Encode(bnry.NewWriter(buf)); err != nil {
+		t.Fatalf("Encode(%v) failed: %v", tup.Data, err)
+	}
+	if err := (&HasTuple{}).Decode(buf); err == nil {
+		t.Fatalf("Decode(%v) without header succeeded, want error", tup.Data)
+	}
+}
+
+func TestHasTuple_encodeComplementBad(t *testing.T) {
+	tup := &HasTuple{Data: HasData{Samples: []int{1, 2, 10}, NSamples: 4}}
+	if err := tup.Encode(bnry.NewWriter(&bytes.Buffer{})); err == nil {
+		t.Fatalf("Encode(%v) succeeded, want error", tup.Data)
+	}
+}
+
+func TestHasTuple_addComplement(t *testing.T) {
+	a := &HasTuple{Data: HasData{Samples: []int{1, 3}, NSamples: 10}}
+	b := &HasTuple{Data: HasData{Samples: []int{2}}}
+	a.Add(b)
+	if a.Data.NSamples != 10 {
+		t.Fatalf("Add(...).NSamples=%v, want 10", a.Data.NSamples)
+	}
+}
+
+func TestHasTuple_addComplementMismatch(t *testing.T) {
+	a := &HasTuple{Data: HasData{Samples: []int{1, 3}, NSamples: 10}}
+	b := &HasTuple{Data: HasData{Samples: []int{2}, NSamples: 12}}
+	a.Add(b)
+	err := NewTupleWriter[HasHandler](&bytes.Buffer{}).Write(a)
+	if err == nil {
+		t.Fatalf("Write(Add(...)) succeeded, want error")
+	}
+	if err := a.Encode(bnry.NewWriter(&bytes.Buffer{})); err == nil {
+		t.Fatalf("Encode(Add(...)) succeeded, want error")
+	}
+}
+
+func TestClusterTuple_encode(t *testing.T) {
+	inputs := []*ClusterTuple{
+		{Kmer: Kmer{1, 2, 3}},
+		{Kmer: Kmer{1, 2, 3}, Data: ClusterData{Members: []Kmer{{1, 2, 3}}}},
+		{Kmer: Kmer{4, 5}, Data: ClusterData{
+			Members: []Kmer{{1, 2, 3}, {4, 5}, {6, 7, 8, 9, 10}}}},
+	}
+	for _, input := range inputs {
+		buf := &bytes.Buffer{}
+		if err := input.Encode(bnry.NewWriter(buf)); err != nil {
+			t.Fatalf("Encode(%v) failed: %v", input, err)
+		}
+		got := NewTuple[ClusterHandler]()
+		if err := got.Decode(buf); err != nil {
+			t.Fatalf("Decode(%v) failed: %v", input, err)
+		}
+		if got.Kmer != input.Kmer ||
+			!slices.Equal(got.Data.Members, input.Data.Members) {
+			t.Fatalf("Decode(Encode(%v))=%v", input, got)
+		}
+	}
+}
+
+func TestBitmapTuple_encode(t *testing.T) {
+	inputs := [][]int{
+		nil,
+		{7},
+		{0, 1000},
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		{3, 64, 65, 100, 127, 128, 129, 130, 200},
+	}
+	for _, input := range inputs {
+		tup := HasToBitmap(&HasTuple{Kmer: Kmer{1, 2},
+			Data: HasData{Samples: input}})
+		buf := &bytes.Buffer{}
+		if err := tup.Encode(bnry.NewWriter(buf)); err != nil {
+			t.Fatalf("Encode(%v) failed: %v", input, err)
+		}
+		got := NewTuple[BitmapHandler]()
+		if err := got.Decode(buf); err != nil {
+			t.Fatalf("Decode(%v) failed: %v", input, err)
+		}
+		if got.Kmer != tup.Kmer {
+			t.Fatalf("Decode(Encode(%v)).Kmer=%v, want %v",
+				input, got.Kmer, tup.Kmer)
+		}
+		if samples := BitmapToHas(got).Data.Samples; !slices.Equal(
+			samples, input) {
+			t.Fatalf("Decode(Encode(%v))=%v", input, samples)
+		}
+	}
+}
+
+func TestBitmapTuple_nsamples(t *testing.T) {
+	input := []*HasTuple{
+		{Kmer: Kmer{1}, Data: HasData{Samples: []int{0, 1, 2, 4}, NSamples: 6}},
+		{Kmer: Kmer{2}, Data: HasData{Samples: []int{3}, NSamples: 6}},
+	}
+	bmp := &bytes.Buffer{}
+	bw := NewTupleWriter[BitmapHandler](bmp)
+	for _, tup := range input {
+		if err := bw.Write(HasToBitmap(tup)); err != nil {
+			t.Fatalf("Write(%v) failed: %v", tup, err)
+		}
+	}
+	has := &bytes.Buffer{}
+	hw := NewTupleWriter[HasHandler](has)
+	for tup, err := range IterTuplesReader[BitmapHandler](bmp) {
+		if err != nil {
+			t.Fatalf("IterTuplesReader() failed: %v", err)
+		}
+		if err := hw.Write(BitmapToHas(tup)); err != nil {
+			t.Fatalf("Write(%v) failed: %v", tup, err)
+		}
+	}
+	i := 0
+	for tup, err := range IterTuplesReader[HasHandler](has) {
+		if err != nil {
+			t.Fatalf("IterTuplesReader() failed: %v", err)
+		}
+		if !hasTuplesEqual(tup, input[i]) ||
+			tup.Data.NSamples != input[i].Data.NSamples {
+			t.Fatalf("BitmapToHas(HasToBitmap(%v))=%v", input[i], tup)
+		}
+		i++
+	}
+	if i != len(input) {
+		t.Fatalf("BitmapToHas(HasToBitmap(...)) returned %v tuples, want %v",
+			i, len(input))
+	}
+}
+
+func TestBitmapTuple_add(t *testing.T) {
+	a := HasToBitmap(&HasTuple{Data: HasData{Samples: []int{5, 1, 3}}})
+	b := HasToBitmap(&HasTuple{Data: HasData{Samples: []int{2, 4}}})
+	a.Add(b)
+	want := []int{1, 2, 3, 4, 5}
+	if got := BitmapToHas(a).Data.Samples; !slices.Equal(got, want) {
+		t.Fatalf("Add(...)=%v, want %v", got, want)
+	}
+}
+
+func TestBitmapTuple_repeated(t *testing.T) {
+	tup := HasToBitmap(&HasTuple{Data: HasData{Samples: []int{5, 1, 5, 3, 1}}})
+	want := []int{1, 3, 5}
+	if got := BitmapToHas(tup).Data.Samples; !slices.Equal(got, want) {
+		t.Fatalf("HasToBitmap(...)=%v, want %v", got, want)
+	}
+}
+
+func TestBitmapTuple_decodeBad(t *testing.T) {
+	inputs := [][]int{
+		{3, 0},  // Repeated, as diffs.
+		{3, -1}, // Unsorted.
+		{-2, 5}, // Negative.
+	}
+	for _, input := range inputs {
+		buf := &bytes.Buffer{}
+		w := bnry.NewWriter(buf)
+		if err := w.Write(make([]byte, K2B), byte(bitmapSparse), input); err != nil {
+			t.Fatalf("Write(%v) failed: %v", input, err)
+		}
+		got := NewTuple[BitmapHandler]()
+		if err := got.Decode(buf); err == nil {
+			t.Errorf("Decode(%v) succeeded, want error", input)
+		}
+	}
+}
+
 func TestDiffs(t *testing.T) {
 	input := []int{5, 10, 13, 27, 100}
 	want := []int{5, 5, 3, 14, 73}
@@ -127,45 +394,3 @@ func countTuplesEqual(a, b *CountTuple) bool {
 func hasTuplesEqual(a, b *HasTuple) bool {
 	return a.Kmer == b.Kmer && slices.Equal(a.Data.Samples, b.Data.Samples)
 }
-
-func TestLinearSort(t *testing.T) {
-	tests := []struct {
-		input []int
-		want  []int
-	}{
-		{nil, nil}, {[]int{4, 3, 0, 1}, []int{0, 1, 3, 4}},
-		{[]int{5, 3, 8, 7}, []int{3, 5, 7, 8}},
-	}
-	for _, test := range tests {
-		linearSort(test.input)
-		if !slices.Equal(test.input, test.want) {
-			t.Errorf("linearSort=%v, want %v", test.input, test.want)
-		}
-	}
-}
-
-func BenchmarkLinearSort(b *testing.B) {
-	s := snm.Slice(50000, func(i int) int { return i })
-	rand.Shuffle(len(s), func(i, j int) { s[i], s[j] = s[j], s[i] })
-	buf := make([]int, len(s))
-	for _, n := range []int{1000, 2000, 3000, 4000, 5000, 10000} {
-		b.Run(fmt.Sprint("lin.", n), func(b *testing.B) {
-			for i := 0; i < b.N; i++ {
-				copy(buf[:n], s)
-				linearSort(buf[:n])
-			}
-		})
-		b.Run(fmt.Sprint("slices.", n), func(b *testing.B) {
-			for i := 0; i < b.N; i++ {
-				copy(buf[:n], s)
-				slices.Sort(buf[:n])
-			}
-		})
-		b.Run(fmt.Sprint("sort.", n), func(b *testing.B) {
-			for i := 0; i < b.N; i++ {
-				copy(buf[:n], s)
-				sort.Ints(buf[:n])
-			}
-		})
-	}
-}
@@ -0,0 +1,86 @@
+// Applies set operations to sorted kmer dump files.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/ptimer"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+)
+
+var (
+	outFile = flag.String("o", "", "Output file")
+	op      = flag.String("s", "union",
+		"Set operation: union, intersect, diff (first minus the rest), "+
+			"symdiff (in an odd number of inputs) or atleast")
+	minIn = flag.Int("n", 0, "Minimal number of inputs for atleast")
+)
+
+// Set operations by the names given in the operation flag.
+var ops = map[string]func(...iter.Seq2[kmr.Kmer, error],
+) iter.Seq2[kmr.Kmer, error]{
+	"union":     kmr.Union,
+	"intersect": kmr.Intersection,
+	"diff":      kmr.Difference,
+	"symdiff":   kmr.SymmetricDifference,
+	"atleast": func(s ...iter.Seq2[kmr.Kmer, error],
+	) iter.Seq2[kmr.Kmer, error] {
+		return kmr.AtLeast(*minIn, s...)
+	},
+}
+
+func main() {
+	util.Die(parseArgs())
+	fmt.Println("Output file:", *outFile)
+
+	var streams []iter.Seq2[kmr.Kmer, error]
+	for _, file := range flag.Args() {
+		fmt.Println("Input file:", file)
+		streams = append(streams, kmr.IterKmersFile(file))
+	}
+
+	fout, err := aio.Create(*outFile)
+	util.Die(err)
+	w := kmr.NewWriter(fout)
+	pt := ptimer.NewMessage("{} kmers written")
+	for kmer, err := range ops[*op](streams...) {
+		util.Die(err)
+		util.Die(w.Write(kmer))
+		pt.Inc()
+	}
+	pt.Done()
+	util.Die(fout.Close())
+
+	fmt.Println("Done")
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"Usage: kset [flags] file1 file2 [file3 ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *outFile == "" {
+		return fmt.Errorf("empty output path")
+	}
+	if ops[*op] == nil {
+		names := []string{"union", "intersect", "diff", "symdiff", "atleast"}
+		return fmt.Errorf("unsupported operation: %q, want one of %s",
+			*op, strings.Join(names, ", "))
+	}
+	if flag.NArg() < 2 {
+		return fmt.Errorf("need at least 2 input files, got %d", flag.NArg())
+	}
+	if *op == "atleast" && (*minIn < 1 || *minIn > flag.NArg()) {
+		return fmt.Errorf("bad minimal number of inputs (-n): %d, want 1-%d",
+			*minIn, flag.NArg())
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Converts KWAS result CSVs to sorted binary result tuples and back.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/bnry"
+	"github.com/fluhus/gostuff/ptimer"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+)
+
+var (
+	inFile  = flag.String("i", "", "Input file (CSV, or binary with -r)")
+	outFile = flag.String("o", "", "Output file (binary, or CSV with -r)")
+	col     = flag.String("c", "kmer", "Name of the tested variable in the CSV")
+	reverse = flag.Bool("r", false, "Convert binary back to CSV")
+)
+
+func main() {
+	util.Die(parseArgs())
+	if *reverse {
+		util.Die(toCSV())
+	} else {
+		util.Die(toBinary())
+	}
+	fmt.Println("Done")
+}
+
+// Reads a result CSV and writes its results sorted by kmer.
+func toBinary() error {
+	fmt.Println("Reading results")
+	f, err := aio.Open(*inFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var results []*kmr.ResultTuple
+	pt := ptimer.New()
+	for t, err := range kmr.IterResultsCSV(f, *col) {
+		if err != nil {
+			return err
+		}
+		results = append(results, t.Clone())
+		pt.Inc()
+	}
+	pt.Done()
+
+	fmt.Println("Writing results")
+	slices.SortFunc(results, func(a, b *kmr.ResultTuple) int {
+		return a.Kmer.Compare(b.Kmer)
+	})
+	for i := 1; i < len(results); i++ {
+		if results[i].Kmer == results[i-1].Kmer {
+			return fmt.Errorf("duplicate kmer: %v", results[i].Kmer)
+		}
+	}
+	fout, err := aio.Create(*outFile)
+	if err != nil {
+		return err
+	}
+	w := bnry.NewWriter(fout)
+	for _, t := range results {
+		if err := t.Encode(w); err != nil {
+			fout.Close()
+			return err
+		}
+	}
+	return fout.Close()
+}
+
+// Writes binary results as CSV.
+func toCSV() error {
+	fout, err := aio.Create(*outFile)
+	if err != nil {
+		return err
+	}
+	err = kmr.WriteResultsCSV(fout, *col,
+		kmr.IterTuplesFile[kmr.ResultHandler](*inFile))
+	if err != nil {
+		fout.Close()
+		return err
+	}
+	return fout.Close()
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *inFile == "" {
+		return fmt.Errorf("empty input path")
+	}
+	if *outFile == "" {
+		return fmt.Errorf("empty output path")
+	}
+	if *col == "" {
+		return fmt.Errorf("empty column name")
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+// Package lsh provides a min-hash locality-sensitive-hashing index over
+// integer sets, such as sample lists.
+//
+// Sketches use one-permutation hashing: each element is hashed once, the hash
+// selects one of k bins, and each bin keeps its minimal hash.
+// Since bins are aligned between sketches, sketches can be split into bands.
+// Sets smaller than k leave some bins empty and have colliding bins, so their
+// sketches also keep the hashes of all of their elements, and two such sets
+// are compared exactly.
+package lsh
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/fluhus/gostuff/bnry"
+	"github.com/fluhus/gostuff/ppln"
+	"github.com/fluhus/kwas/util"
+	"github.com/spaolacci/murmur3"
+)
+
+// Empty is the value of a bin with no elements.
+const Empty = ^uint64(0)
+
+// A Sketch is a min-hash signature of a set.
+type Sketch struct {
+	bins   []uint64 // Minimal hash of each bin, or Empty.
+	hashes []uint64 // Sorted hashes of all elements, while fewer than k.
+	full   bool     // The set has at least k elements, hashes is nil.
+}
+
+// NewSketch returns the k-bin sketch of the given set.
+// Safe for concurrent use.
+func NewSketch(set []int, k int) *Sketch {
+	s := EmptySketch(k)
+	for _, x := range set {
+		s.Add(x)
+	}
+	return s
+}
+
+// EmptySketch returns the k-bin sketch of an empty set, for adding elements
+// one by one.
+func EmptySketch(k int) *Sketch {
+	if k < 1 {
+		panic(fmt.Sprintf("bad k: %d", k))
+	}
+	s := &Sketch{bins: make([]uint64, k)}
+	for i := range s.bins {
+		s.bins[i] = Empty
+	}
+	return s
+}
+
+// Add adds x to the sketched set.
+// Safe for concurrent use on different sketches.
+func (s *Sketch) Add(x int) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(x))
+	h := murmur3.Sum64(buf[:])
+	if h == Empty { // Reserved.
+		h--
+	}
+	bin := h % uint64(len(s.bins))
+	s.bins[bin] = min(s.bins[bin], h)
+	if s.full {
+		return
+	}
+	if i, ok := slices.BinarySearch(s.hashes, h); !ok {
+		s.hashes = slices.Insert(s.hashes, i, h)
+	}
+	if len(s.hashes) >= len(s.bins) {
+		s.hashes = nil
+		s.full = true
+	}
+}
+
+// K returns the number of bins in this sketch.
+func (s *Sketch) K() int {
+	return len(s.bins)
+}
+
+// Bins returns the minimal hash of each bin, or Empty for bins with no
+// elements.
+func (s *Sketch) Bins() []uint64 {
+	return s.bins
+}
+
+// Len returns the number of non-empty bins.
+func (s *Sketch) Len() int {
+	n := 0
+	for _, h := range s.bins {
+		if h != Empty {
+			n++
+		}
+	}
+	return n
+}
+
+// Shared returns the number of non-empty bins that are equal in both sketches.
+func (s *Sketch) Shared(other *Sketch) int {
+	s.checkK(other)
+	n := 0
+	for i, h := range s.bins {
+		if h != Empty && h == other.bins[i] {
+			n++
+		}
+	}
+	return n
+}
+
+// Jaccard returns the estimated Jaccard similarity of the two sketched sets.
+// If both sets have fewer than k elements, the similarity is exact.
+func (s *Sketch) Jaccard(other *Sketch) float64 {
+	s.checkK(other)
+	if !s.full && !other.full {
+		return exactJaccard(s.hashes, other.hashes)
+	}
+	shared, union := 0, 0
+	for i, h := range s.bins {
+		if h == Empty && other.bins[i] == Empty {
+			continue
+		}
+		union++
+		if h == other.bins[i] {
+			shared++
+		}
+	}
+	if union == 0 { // Both sets are empty.
+		return 1
+	}
+	return float64(shared) / float64(union)
+}
+
+// Panics if the sketches have different numbers of bins.
+func (s *Sketch) checkK(other *Sketch) {
+	if len(s.bins) != len(other.bins) {
+		panic(fmt.Sprintf("mismatching sketch lengths: %d, %d",
+			len(s.bins), len(other.bins)))
+	}
+}
+
+// Returns the Jaccard similarity of two sorted lists of unique hashes.
+func exactJaccard(a, b []uint64) float64 {
+	if len(a) == 0 && len(b) == 0 { // Both sets are empty.
+		return 1
+	}
+	shared := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			shared++
+			i++
+			j++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// Index finds sketches that share bins with a query.
+type Index struct {
+	k        int
+	bands    int
+	sketches []*Sketch
+	buckets  []map[uint64][]int // Band hash to IDs, per band.
+}
+
+// New returns an empty index for k-bin sketches, split into the given number
+// of bands. Two sketches are candidates if they agree on at least one whole
+// band, so more bands find more remote pairs. Bands must divide k.
+func New(k, bands int) *Index {
+	if k < 1 {
+		panic(fmt.Sprintf("bad k: %d", k))
+	}
+	if bands < 1 || k%bands != 0 {
+		panic(fmt.Sprintf("bad number of bands: %d, want a divisor of %d",
+			bands, k))
+	}
+	idx := &Index{k: k, bands: bands}
+	for range bands {
+		idx.buckets = append(idx.buckets, map[uint64][]int{})
+	}
+	return idx
+}
+
+// K returns the number of bins in this index's sketches.
+func (idx *Index) K() int {
+	return idx.k
+}
+
+// Bands returns the number of bands in this index.
+func (idx *Index) Bands() int {
+	return idx.bands
+}
+
+// Len returns the number of sketches in this index.
+func (idx *Index) Len() int {
+	return len(idx.sketches)
+}
+
+// Sketch returns the sketch with the given ID.
+func (idx *Index) Sketch(id int) *Sketch {
+	return idx.sketches[id]
+}
+
+// Add adds the given sketch and returns its ID.
+// IDs are consecutive, starting from 0.
+func (idx *Index) Add(s *Sketch) int {
+	if s.K() != idx.k {
+		panic(fmt.Sprintf("bad sketch length: %d, want %d", s.K(), idx.k))
+	}
+	id := len(idx.sketches)
+	idx.sketches = append(idx.sketches, s)
+	for i, b := range idx.bandHashes(s) {
+		if b != Empty {
+			idx.buckets[i][b] = append(idx.buckets[i][b], id)
+		}
+	}
+	return id
+}
+
+// AddSets sketches the given sets using nt goroutines and adds them in order.
+// Returns the ID of the first set.
+func (idx *Index) AddSets(sets [][]int, nt int) int {
+	first := idx.Len()
+	ppln.Serial(nt,
+		func(push func(int), stop func() bool) error {
+			for i := range sets {
+				push(i)
+			}
+			return nil
+		},
+		func(a, i, g int) (*Sketch, error) {
+			return NewSketch(sets[a], idx.k), nil
+		},
+		func(s *Sketch) error {
+			idx.Add(s)
+			return nil
+		})
+	return first
+}
+
+// Query returns the IDs of the sketches whose estimated Jaccard similarity
+// with s is at least minShared/k, in ascending order. For sets with at least
+// k elements, this is sharing at least minShared bins. Sets with fewer than k
+// elements are compared exactly.
+// Safe for concurrent use, as long as no sketches are being added.
+func (idx *Index) Query(s *Sketch, minShared int) []int {
+	return idx.query(s, minShared, -1)
+}
+
+// QueryID is like Query with an indexed sketch, excluding the sketch itself.
+func (idx *Index) QueryID(id, minShared int) []int {
+	return idx.query(idx.sketches[id], minShared, id)
+}
+
+// QueryAll calls fn with the result of QueryID for every indexed sketch,
+// using nt goroutines. Fn is called concurrently from multiple goroutines,
+// in an arbitrary order.
+func (idx *Index) QueryAll(minShared, nt int,
+	fn func(id int, hits []int) error) error {
+	if nt < 1 {
+		panic(fmt.Sprintf("bad number of goroutines: %d", nt))
+	}
+	var err error
+	var errl sync.Mutex
+	var wg sync.WaitGroup
+	for g := range nt {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := g; id < idx.Len(); id += nt {
+				if e := fn(id, idx.QueryID(id, minShared)); e != nil {
+					errl.Lock()
+					if err == nil {
+						err = e
+					}
+					errl.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return err
+}
+
+// Returns the IDs that share enough bins with s, excluding self.
+func (idx *Index) query(s *Sketch, minShared int, self int) []int {
+	if s.K() != idx.k {
+		panic(fmt.Sprintf("bad sketch length: %d, want %d", s.K(), idx.k))
+	}
+	var cands []int
+	for i, b := range idx.bandHashes(s) {
+		if b != Empty {
+			cands = append(cands, idx.buckets[i][b]...)
+		}
+	}
+	slices.Sort(cands)
+	cands = slices.Compact(cands)
+
+	minSim := float64(minShared) / float64(idx.k)
+	result := cands[:0]
+	for _, c := range cands {
+		if c == self {
+			continue
+		}
+		if s.Jaccard(idx.sketches[c]) >= minSim {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Returns the hash of each band in s, or Empty for bands with only empty
+// bins.
+func (idx *Index) bandHashes(s *Sketch) []uint64 {
+	r := idx.k / idx.bands
+	result := make([]uint64, idx.bands)
+	buf := make([]byte, 0, r*8)
+	for i := range result {
+		band := s.bins[i*r : (i+1)*r]
+		if r == 1 { // No need to hash.
+			result[i] = band[0]
+			continue
+		}
+		empty := true
+		buf = buf[:0]
+		for _, h := range band {
+			empty = empty && h == Empty
+			buf = binary.LittleEndian.AppendUint64(buf, h)
+		}
+		if empty {
+			result[i] = Empty
+			continue
+		}
+		result[i] = murmur3.Sum64(buf)
+		if result[i] == Empty { // Reserved.
+			result[i]--
+		}
+	}
+	return result
+}
+
+// Encode writes the index's parameters and sketches.
+func (idx *Index) Encode(w io.Writer) error {
+	bw := bnry.NewWriter(w)
+	if err := bw.Write(idx.k, idx.bands, len(idx.sketches)); err != nil {
+		return err
+	}
+	for _, s := range idx.sketches {
+		if err := bw.Write(s.bins, s.full, s.hashes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode reads an index that was written with Encode.
+func Decode(r io.ByteReader) (*Index, error) {
+	var k, bands, n int
+	if err := bnry.Read(r, &k, &bands, &n); err != nil {
+		return nil, err
+	}
+	if k < 1 || bands < 1 || k%bands != 0 || n < 0 {
+		return nil, fmt.Errorf("bad index parameters: k=%d bands=%d n=%d",
+			k, bands, n)
+	}
+	idx := New(k, bands)
+	for i := range n {
+		s := &Sketch{}
+		if err := bnry.Read(r, &s.bins, &s.full, &s.hashes); err != nil {
+			return nil, fmt.Errorf("sketch #%d: %w", i+1,
+				util.NotExpectingEOF(err))
+		}
+		if len(s.bins) != k {
+			return nil, fmt.Errorf("sketch #%d: bad length: %d, want %d",
+				i+1, len(s.bins), k)
+		}
+		if s.full && len(s.hashes) > 0 || len(s.hashes) >= k {
+			return nil, fmt.Errorf("sketch #%d: bad number of hashes: %d",
+				i+1, len(s.hashes))
+		}
+		for j := range s.hashes[min(len(s.hashes), 1):] {
+			if s.hashes[j] >= s.hashes[j+1] {
+				return nil, fmt.Errorf("sketch #%d: hashes are not sorted",
+					i+1)
+			}
+		}
+		idx.Add(s)
+	}
+	return idx, nil
+}
@@ -0,0 +1,161 @@
+package lsh
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/fluhus/gostuff/gnum"
+	"github.com/fluhus/gostuff/snm"
+)
+
+func TestSketch_small(t *testing.T) {
+	a := NewSketch([]int{1, 5, 9}, 50)
+	b := NewSketch([]int{9, 5, 1}, 50)
+	if !sketchesEqual(a, b) {
+		t.Fatalf("NewSketch(...)=%v, want %v", b, a)
+	}
+	if a.Len() != 3 {
+		t.Fatalf("Len()=%d, want 3", a.Len())
+	}
+	if got := a.Shared(b); got != 3 {
+		t.Fatalf("Shared(...)=%d, want 3", got)
+	}
+	if got := a.Jaccard(b); got != 1 {
+		t.Fatalf("Jaccard(...)=%f, want 1", got)
+	}
+	c := EmptySketch(50)
+	for _, x := range []int{5, 1, 9, 5} {
+		c.Add(x)
+	}
+	if !sketchesEqual(a, c) {
+		t.Fatalf("Add(...)=%v, want %v", c, a)
+	}
+	if got := NewSketch(nil, 50).Len(); got != 0 {
+		t.Fatalf("NewSketch(nil).Len()=%d, want 0", got)
+	}
+}
+
+func TestSketch_jaccard(t *testing.T) {
+	a := snm.Slice(2000, func(i int) int { return i })
+	b := snm.Slice(2000, func(i int) int { return i + 1000 })
+	got := NewSketch(a, 200).Jaccard(NewSketch(b, 200))
+	const want = 1.0 / 3.0
+	if gnum.Abs(got-want) > 0.1 {
+		t.Fatalf("Jaccard(...)=%f, want ~%f", got, want)
+	}
+}
+
+func TestSketch_jaccardSmall(t *testing.T) {
+	a := NewSketch([]int{1, 2, 3, 4}, 50)
+	b := NewSketch([]int{2, 3, 4, 5, 6}, 50)
+	if got, want := a.Jaccard(b), 0.5; got != want {
+		t.Fatalf("Jaccard(...)=%f, want %f", got, want)
+	}
+	big := NewSketch(snm.Slice(50, func(i int) int { return i }), 50)
+	if big.full != true || big.hashes != nil {
+		t.Fatalf("NewSketch(50 elements) kept %d hashes, want none",
+			len(big.hashes))
+	}
+}
+
+// Near-identical small sets, which collide in their bins, should be found.
+func TestIndex_recallSmall(t *testing.T) {
+	const n = 1000
+	for _, size := range []int{10, 20, 40, 100} {
+		idx := New(50, 50)
+		for i := range n {
+			set := snm.Slice(size, func(j int) int { return i*1000 + j })
+			idx.Add(NewSketch(set, 50))
+			set[0] = i*1000 + 999 // Differs in one element.
+			idx.Add(NewSketch(set, 50))
+		}
+		found := 0
+		for i := range n {
+			if slices.Contains(idx.QueryID(2*i, 37), 2*i+1) {
+				found++
+			}
+		}
+		// Jaccard is at least 9/11, above 37/50.
+		if found < n*99/100 {
+			t.Errorf("size=%d: found %d/%d pairs, want at least %d",
+				size, found, n, n*99/100)
+		}
+	}
+}
+
+func TestIndex_query(t *testing.T) {
+	for _, bands := range []int{10, 25, 50} {
+		idx := New(50, bands)
+		base := rand.Perm(10000)[:1000]
+		near := slices.Clone(base)
+		near[0] = 10001
+		far := rand.Perm(10000)[:1000]
+		idx.AddSets([][]int{base, far, near, {1, 2}}, 2)
+
+		if got, want := idx.QueryID(0, 37), []int{2}; !slices.Equal(got, want) {
+			t.Errorf("bands=%d: QueryID(0)=%v, want %v", bands, got, want)
+		}
+		if got, want := idx.QueryID(1, 37), []int{}; !slices.Equal(got, want) {
+			t.Errorf("bands=%d: QueryID(1)=%v, want %v", bands, got, want)
+		}
+		small := NewSketch([]int{1, 2}, 50)
+		if got, want := idx.Query(small, 37), []int{3}; !slices.Equal(got, want) {
+			t.Errorf("bands=%d: Query({1,2})=%v, want %v", bands, got, want)
+		}
+	}
+}
+
+func TestIndex_queryAll(t *testing.T) {
+	idx := New(20, 20)
+	idx.AddSets([][]int{{1, 2, 3}, {4, 5, 6}, {1, 2, 3}, {4, 5, 6}}, 1)
+	want := [][]int{{2}, {3}, {0}, {1}}
+	got := make([][]int, 4)
+	var mu sync.Mutex
+	err := idx.QueryAll(3, 3, func(id int, hits []int) error {
+		mu.Lock()
+		got[id] = hits
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("QueryAll(...) failed: %v", err)
+	}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("QueryAll(...)=%v, want %v", got, want)
+	}
+}
+
+func TestIndex_encode(t *testing.T) {
+	idx := New(10, 2)
+	idx.AddSets([][]int{{1, 2, 3}, {4, 5, 6}, nil, {1, 2, 3}}, 1)
+	buf := &bytes.Buffer{}
+	if err := idx.Encode(buf); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	got, err := Decode(buf)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if got.K() != idx.K() || got.Bands() != idx.Bands() ||
+		got.Len() != idx.Len() {
+		t.Fatalf("Decode()=(k=%d,bands=%d,len=%d), want (%d,%d,%d)",
+			got.K(), got.Bands(), got.Len(), idx.K(), idx.Bands(), idx.Len())
+	}
+	for i := range idx.Len() {
+		if !sketchesEqual(got.Sketch(i), idx.Sketch(i)) {
+			t.Fatalf("Decode().Sketch(%d)=%v, want %v",
+				i, got.Sketch(i), idx.Sketch(i))
+		}
+	}
+	if q := got.QueryID(0, 3); !slices.Equal(q, []int{3}) {
+		t.Fatalf("Decode().QueryID(0)=%v, want [3]", q)
+	}
+}
+
+func sketchesEqual(a, b *Sketch) bool {
+	return slices.Equal(a.bins, b.bins) && a.full == b.full &&
+		slices.Equal(a.hashes, b.hashes)
+}
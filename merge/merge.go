@@ -4,8 +4,11 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"iter"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 
 	"github.com/fluhus/gostuff/aio"
@@ -19,7 +22,10 @@ var (
 	del = flag.Bool("d", false, "Delete input files when done")
 	in  = flag.String("i", "", "Input file pattern")
 	out = flag.String("o", "", "Output file")
-	typ = flag.String("t", "", "Type of files being merged")
+	typ = flag.String("t", "", "Type of files being merged (has, cnt, ...)")
+	ns  = flag.Int("n", 0,
+		"Number of samples, for encoding common kmers by their absent samples"+
+			" (has only)")
 )
 
 func main() {
@@ -36,17 +42,16 @@ func main() {
 
 	fmt.Printf("Reading %v files out of %v\n", len(files), nfiles)
 
-	switch *typ {
-	case "cnt":
-		err = merge[kmr.CountHandler](files)
-	case "has":
-		err = merge[kmr.HasHandler](files)
-	case "prf":
-		err = merge[kmr.ProfileHandler](files)
-	default:
-		err = fmt.Errorf("unsupported type: %q", *typ)
+	fmt.Println("Writing to:", *out)
+	fout, err := aio.Create(*out)
+	util.Die(err)
+	if *typ == "has" && *ns > 0 {
+		err = mergeHas(files, fout)
+	} else {
+		err = kmr.MergeFiles(*typ, files, fout)
 	}
 	util.Die(err)
+	util.Die(fout.Close())
 
 	if *del {
 		fmt.Println("Removing input files")
@@ -59,22 +64,36 @@ func main() {
 	fmt.Println("Done")
 }
 
-// Merges the given files using the given zero tuple.
-func merge[H kmr.KmerDataHandler[T], T any](files []string) error {
-	m := kmr.NewMerger[H]()
+// Merges HAS files, encoding common kmers by their absent samples.
+func mergeHas(files []string, w io.Writer) error {
+	m := kmr.NewMerger[kmr.HasHandler]()
 	for _, file := range files {
-		if err := m.Add(kmr.IterTuplesFile[H](file)); err != nil {
+		if err := m.Add(withNSamples(
+			kmr.IterTuplesFile[kmr.HasHandler](file))); err != nil {
 			return err
 		}
 	}
+	return m.Dump(w)
+}
 
-	fmt.Println("Writing to:", *out)
-	fout, err := aio.Create(*out)
-	if err != nil {
-		return err
+// Sets the number of samples on each tuple of seq.
+// Fails on tuples that were encoded with a different number of samples.
+func withNSamples(seq iter.Seq2[*kmr.HasTuple, error],
+) iter.Seq2[*kmr.HasTuple, error] {
+	return func(yield func(*kmr.HasTuple, error) bool) {
+		for t, err := range seq {
+			if err == nil {
+				if t.Data.NSamples > 0 && t.Data.NSamples != *ns {
+					err = fmt.Errorf("tuple has %d samples, want %d",
+						t.Data.NSamples, *ns)
+				}
+				t.Data.NSamples = *ns
+			}
+			if !yield(t, err) {
+				return
+			}
+		}
 	}
-	defer fout.Close()
-	return m.Dump(fout)
 }
 
 func parseArgs() error {
@@ -85,5 +104,9 @@ func parseArgs() error {
 	if *out == "" {
 		return fmt.Errorf("empty output path")
 	}
+	if !slices.Contains(kmr.Registered(), *typ) {
+		return fmt.Errorf("unsupported type: %q, want one of %v",
+			*typ, kmr.Registered())
+	}
 	return nil
 }
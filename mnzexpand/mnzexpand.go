@@ -0,0 +1,85 @@
+// Expands cluster centers back to all the kmers in their clusters.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+
+	"github.com/fluhus/biostuff/sequtil"
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/ptimer"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+)
+
+var (
+	inFile   = flag.String("i", "", "Input kmer list file, one kmer per line")
+	clusters = flag.String("c", "", "Cluster member files glob (mnzgraph -b)")
+	outFile  = flag.String("o", "", "Output kmer list file")
+)
+
+func main() {
+	util.Die(parseArgs())
+
+	fmt.Println("Reading clusters")
+	pt := ptimer.New()
+	members, err := kmr.ReadClustersFiles(*clusters)
+	util.Die(err)
+	pt.Done()
+	fmt.Println("Found", len(members), "clusters")
+
+	fmt.Println("Expanding")
+	kmers, err := util.ReadLines(aio.Open(*inFile))
+	util.Die(err)
+	fout, err := aio.Create(*outFile)
+	util.Die(err)
+	w := bufio.NewWriter(fout)
+
+	pt = ptimer.New()
+	notFound := 0
+	var buf []byte
+	for _, kmer := range kmers {
+		if len(kmer) != kmr.K {
+			util.Die(fmt.Errorf("bad kmer length: %d, want %d",
+				len(kmer), kmr.K))
+		}
+		buf = sequtil.DNATo2Bit(buf[:0], []byte(kmer))
+		m, ok := members[kmr.Kmer(buf)]
+		if !ok {
+			notFound++
+			fmt.Fprintln(w, kmer)
+			continue
+		}
+		for _, mm := range m {
+			buf = sequtil.DNAFrom2Bit(buf[:0], mm[:])[:kmr.K]
+			w.Write(buf)
+			w.WriteByte('\n')
+		}
+		pt.Inc()
+	}
+	util.Die(w.Flush())
+	util.Die(fout.Close())
+	pt.Done()
+	if notFound > 0 {
+		fmt.Println(notFound, "kmers are not cluster centers,",
+			"copied them as they are")
+	}
+
+	fmt.Println("Done")
+}
+
+// Parses program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *inFile == "" {
+		return fmt.Errorf("empty input path")
+	}
+	if *clusters == "" {
+		return fmt.Errorf("empty clusters path")
+	}
+	if *outFile == "" {
+		return fmt.Errorf("empty output path")
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Cluster center selection.
+
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/fluhus/gostuff/gnum"
+	"github.com/fluhus/gostuff/snm"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+)
+
+// A cluster representative and the kmers it represents.
+type cluster struct {
+	center  *kmr.HasTuple
+	members []kmr.Kmer
+}
+
+// Returns the representatives of a single component.
+func componentCenters(comp []*kmr.HasTuple) []cluster {
+	var d []float64
+	if len(comp) > *approxAbove {
+		d = approxSqDistances(comp, sampleRefs(comp, *approxRefs))
+	} else {
+		d = sqDistances(comp)
+	}
+	if *centerMode == "consensus" {
+		members := snm.SliceToSlice(comp, func(t *kmr.HasTuple) kmr.Kmer {
+			return t.Kmer
+		})
+		return []cluster{{consensus(comp, comp[util.ArgMin(d)]), members}}
+	}
+	return representatives(comp, d, *nReps)
+}
+
+// Returns up to n representatives of the component.
+// The first is the medoid (minimal d), and each next one is the member that
+// is farthest from the ones already chosen.
+// Each member is assigned to its nearest representative.
+func representatives(comp []*kmr.HasTuple, d []float64, n int) []cluster {
+	reps := []int{util.ArgMin(d)}
+	owner := make([]int, len(comp)) // Index in reps.
+
+	// Distance of each member from its nearest chosen representative.
+	nearest := make([]float64, len(comp))
+	for i := range nearest {
+		nearest[i] = math.Inf(1)
+	}
+	for {
+		irep := len(reps) - 1
+		rep := comp[reps[irep]]
+		for i, k := range comp {
+			d := dist(rep.Data.Samples, k.Data.Samples, *nSamples)
+			if d < nearest[i] {
+				nearest[i] = d
+				owner[i] = irep
+			}
+		}
+		if len(reps) == n {
+			break
+		}
+		i := util.ArgMax(nearest)
+		if nearest[i] == 0 { // All members are covered.
+			break
+		}
+		reps = append(reps, i)
+	}
+
+	result := snm.SliceToSlice(reps, func(i int) cluster {
+		return cluster{center: comp[i]}
+	})
+	for i, k := range comp {
+		result[owner[i]].members = append(result[owner[i]].members, k.Kmer)
+	}
+	return result
+}
+
+// Returns a tuple with center's kmer, and the samples that are present in
+// at least half of the component's members.
+func consensus(comp []*kmr.HasTuple, center *kmr.HasTuple) *kmr.HasTuple {
+	counts := map[int]int{}
+	for _, k := range comp {
+		for _, s := range k.Data.Samples {
+			counts[s]++
+		}
+	}
+	var samples []int
+	for s, c := range counts {
+		if c*2 >= len(comp) {
+			samples = append(samples, s)
+		}
+	}
+	slices.Sort(samples)
+	result := center.Clone()
+	result.Data.Samples = samples
+	return result
+}
+
+// Returns the root mean square distance from each tuple to the rest.
+func sqDistances(kmers []*kmr.HasTuple) []float64 {
+	result := make([]float64, len(kmers))
+	for i, ki := range kmers {
+		for j, kj := range kmers[i+1:] {
+			d := dist(ki.Data.Samples, kj.Data.Samples, *nSamples)
+			d *= d
+			result[i] += d
+			result[j+i+1] += d
+		}
+	}
+	gnum.Mul1(result, 1.0/float64(len(kmers)-1))
+	for i := range result {
+		result[i] = math.Sqrt(result[i])
+	}
+	return result
+}
+
+// Returns the root mean square distance from each tuple to the reference
+// tuples. An approximation of sqDistances when refs is a random sample.
+func approxSqDistances(kmers, refs []*kmr.HasTuple) []float64 {
+	result := make([]float64, len(kmers))
+	for i, k := range kmers {
+		for _, r := range refs {
+			d := dist(k.Data.Samples, r.Data.Samples, *nSamples)
+			result[i] += d * d
+		}
+	}
+	gnum.Mul1(result, 1.0/float64(len(refs)))
+	for i := range result {
+		result[i] = math.Sqrt(result[i])
+	}
+	return result
+}
+
+// Returns n random members of the component.
+// The sample is deterministic given the component.
+func sampleRefs(comp []*kmr.HasTuple, n int) []*kmr.HasTuple {
+	if n >= len(comp) {
+		return comp
+	}
+	rnd := rand.New(rand.NewPCG(uint64(len(comp)), uint64(n)))
+	perm := rnd.Perm(len(comp))[:n]
+	refs := make([]*kmr.HasTuple, n)
+	for i, j := range perm {
+		refs[i] = comp[j]
+	}
+	return refs
+}
@@ -4,7 +4,6 @@ import (
 	"flag"
 	"fmt"
 	"io/fs"
-	"math"
 	"path/filepath"
 	"slices"
 	"sync"
@@ -12,56 +11,114 @@ import (
 	"github.com/fluhus/biostuff/sequtil"
 	"github.com/fluhus/gostuff/aio"
 	"github.com/fluhus/gostuff/bnry"
-	"github.com/fluhus/gostuff/gnum"
 	"github.com/fluhus/gostuff/jio"
-	"github.com/fluhus/gostuff/minhash"
 	"github.com/fluhus/gostuff/ppln"
 	"github.com/fluhus/gostuff/ptimer"
 	"github.com/fluhus/gostuff/snm"
 	"github.com/fluhus/kwas/graphs"
 	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/lsh"
 	"github.com/fluhus/kwas/util"
 )
 
 const (
 	assertSamplesSorted = false // For debugging.
-
-	indexK       = 50
-	indexSearchK = 37
 )
 
 var (
-	input    = flag.String("i", "", "Input file glob pattern")
-	output   = flag.String("o", "", "Output file")
-	joutput  = flag.String("j", "", "Optional output JSON file for cluster")
+	input   = flag.String("i", "", "Input file glob pattern")
+	output  = flag.String("o", "", "Output file")
+	joutput = flag.String("j", "", "Optional output JSON file for cluster")
+	boutput = flag.String("b", "",
+		"Optional output binary file mapping centers to cluster members")
 	nt       = flag.Int("t", 1, "Number of threads")
 	nSamples = flag.Int("n", 0, "Total number of samples")
+	metric   = flag.String("m", "dual",
+		"Distance metric: dual (Jaccard), jaccard or r2; candidate pairs "+
+			"come from the min-hash index, so Hamming distance is not "+
+			"supported")
+	thr          = flag.Float64("d", 0.05, "Maximal distance for an edge")
+	indexK       = flag.Int("mk", 50, "Number of min-hashes per kmer")
+	indexSearchK = flag.Int("ms", 37,
+		"Minimal number of shared min-hashes for comparing two kmers; "+
+			"kmers in fewer than -mk samples need a Jaccard of -ms/-mk")
+	indexBands = flag.Int("mb", 50,
+		"Number of min-hash bands, kmers are compared if they share a band")
+	centerMode = flag.String("c", "medoid",
+		"Center selection: medoid or consensus (majority presence vector)")
+	nReps       = flag.Int("r", 1, "Number of representatives per component")
+	approxAbove = flag.Int("a", 1000,
+		"Approximate medoids of components larger than this size")
+	approxRefs = flag.Int("as", 200,
+		"Number of sampled members for approximate medoids")
 )
 
+// Distance functions by the names given in the metric flag.
+// n is the total number of samples.
+//
+// Candidate pairs are kmers with similar sample sets, so a metric is only
+// valid if close kmers have a high Jaccard similarity. Hamming distance is
+// not, since any two rare kmers are close.
+var metrics = map[string]func(a, b []int, n int) float64{
+	"dual": util.JaccardDualDist,
+	"jaccard": func(a, b []int, n int) float64 {
+		return util.JaccardDist(a, b)
+	},
+	"r2": util.R2Dist,
+}
+
+// The selected distance function.
+var dist func(a, b []int, n int) float64
+
+// Parameters of this run, saved next to the output for reproducibility.
+type runParams struct {
+	Input        string
+	Metric       string
+	Threshold    float64
+	NSamples     int
+	IndexK       int
+	IndexSearchK int
+	IndexBands   int
+	CenterMode   string
+	NReps        int
+	ApproxAbove  int
+	ApproxRefs   int
+}
+
 func main() {
-	flag.Parse()
+	util.Die(parseArgs())
+	params := runParams{
+		Input:        *input,
+		Metric:       *metric,
+		Threshold:    *thr,
+		NSamples:     *nSamples,
+		IndexK:       *indexK,
+		IndexSearchK: *indexSearchK,
+		IndexBands:   *indexBands,
+		CenterMode:   *centerMode,
+		NReps:        *nReps,
+		ApproxAbove:  *approxAbove,
+		ApproxRefs:   *approxRefs,
+	}
+	fmt.Printf("Parameters: %+v\n", params)
 
 	kmers, err := loadKmersGlob(*input)
 	util.Die(err)
 
-	var mhs [][]uint64
-	var idx mhindex
-
 	pt := ptimer.NewMessage("{} kmers indexed")
-	idx = mhindex{}
-	ppln.Serial[int, []uint64](*nt,
+	idx := lsh.New(*indexK, *indexBands)
+	ppln.Serial[int, *lsh.Sketch](*nt,
 		func(push func(int), stop func() bool) error {
 			for i := range kmers {
 				push(i)
 			}
 			return nil
 		},
-		func(a, i, g int) ([]uint64, error) {
-			return intsMinHash(kmers[a].Data.Samples, indexK), nil
+		func(a, i, g int) (*lsh.Sketch, error) {
+			return lsh.NewSketch(kmers[a].Data.Samples, *indexK), nil
 		},
-		func(mh []uint64) error {
-			mhs = append(mhs, slices.Clone(mh))
-			idx.add(pt.N, mh)
+		func(mh *lsh.Sketch) error {
+			idx.Add(mh)
 			pt.Inc()
 			return nil
 		})
@@ -79,10 +136,12 @@ func main() {
 			return nil
 		},
 		func(a int, push func([2]int), g int) error {
-			const thr = 0.05
-			for _, i := range idx.search(a, mhs[a], indexSearchK) {
-				if util.JaccardDualDist(kmers[a].Data.Samples,
-					kmers[i].Data.Samples, *nSamples) < thr {
+			for _, i := range candidates(idx, kmers, a) {
+				if i < a { // Avoid pair repetition.
+					continue
+				}
+				if dist(kmers[a].Data.Samples,
+					kmers[i].Data.Samples, *nSamples) < *thr {
 					push([2]int{a, i})
 				}
 			}
@@ -115,7 +174,7 @@ func main() {
 	}
 	fmt.Println("Component size quantiles:", util.NTiles(20, lens))
 
-	centers := make([]*kmr.HasTuple, 0, len(comps))
+	centers := make([]cluster, 0, len(comps))
 	pt = ptimer.NewMessage("{} centers calculated")
 	ppln.Serial(*nt,
 		func(push func([]int), _ func() bool) error {
@@ -125,12 +184,11 @@ func main() {
 			}
 			return nil
 		},
-		func(a []int, i, g int) (*kmr.HasTuple, error) {
-			compKmers := snm.At(kmers, a)
-			return compKmers[util.ArgMin(sqDistances(compKmers))], nil
+		func(a []int, i, g int) ([]cluster, error) {
+			return componentCenters(snm.At(kmers, a)), nil
 		},
-		func(a *kmr.HasTuple) error {
-			centers = append(centers, a)
+		func(a []cluster) error {
+			centers = append(centers, a...)
 			return nil
 		},
 	)
@@ -139,12 +197,19 @@ func main() {
 	fmt.Println("Saving centers")
 	fout, err := aio.Create(*output)
 	util.Die(err)
-	w := bnry.NewWriter(fout)
+	w := kmr.NewTupleWriter[kmr.HasHandler](fout)
 	for _, c := range centers {
-		util.Die(c.Encode(w))
+		c.center.Data.NSamples = *nSamples
+		util.Die(w.Write(c.center))
 	}
 	fout.Close()
 
+	if *boutput != "" {
+		fmt.Println("Saving cluster members")
+		util.Die(saveClusters(*boutput, centers))
+	}
+	util.Die(jio.Save(*output+".params.json", params))
+
 	// Print to JSON for minimizer plots.
 	if *joutput != "" {
 		fmt.Println("Converting to JSON")
@@ -165,6 +230,78 @@ func main() {
 	fmt.Println("Done")
 }
 
+// Returns the IDs of the kmers that may be close to kmer a.
+// Under r2, anticorrelated kmers are close too, so these are looked up by
+// the samples that do not have kmer a.
+func candidates(idx *lsh.Index, kmers []*kmr.HasTuple, a int) []int {
+	result := idx.QueryID(a, *indexSearchK)
+	if *metric != "r2" {
+		return result
+	}
+	has := make([]bool, *nSamples)
+	for _, s := range kmers[a].Data.Samples {
+		if s < len(has) {
+			has[s] = true
+		}
+	}
+	comp := lsh.EmptySketch(*indexK)
+	for i, h := range has {
+		if !h {
+			comp.Add(i)
+		}
+	}
+	for _, i := range idx.Query(comp, *indexSearchK) {
+		if i != a {
+			result = append(result, i)
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *input == "" {
+		return fmt.Errorf("empty input path")
+	}
+	if *output == "" {
+		return fmt.Errorf("empty output path")
+	}
+	dist = metrics[*metric]
+	if dist == nil {
+		return fmt.Errorf("unsupported metric: %q", *metric)
+	}
+	if *thr < 0 || *thr > 1 {
+		return fmt.Errorf("bad threshold: %v, want 0-1", *thr)
+	}
+	if *indexK < 1 {
+		return fmt.Errorf("bad number of min-hashes: %d", *indexK)
+	}
+	if *indexSearchK < 1 || *indexSearchK > *indexK {
+		return fmt.Errorf("bad number of shared min-hashes: %d, want 1-%d",
+			*indexSearchK, *indexK)
+	}
+	if *indexBands < 1 || *indexK%*indexBands != 0 {
+		return fmt.Errorf("bad number of bands: %d, want a divisor of %d",
+			*indexBands, *indexK)
+	}
+	if *centerMode != "medoid" && *centerMode != "consensus" {
+		return fmt.Errorf("unsupported center mode: %q", *centerMode)
+	}
+	if *nReps < 1 {
+		return fmt.Errorf("bad number of representatives: %d", *nReps)
+	}
+	if *centerMode == "consensus" && *nReps != 1 {
+		return fmt.Errorf("consensus mode supports 1 representative, got %d",
+			*nReps)
+	}
+	if *approxRefs < 1 {
+		return fmt.Errorf("bad number of sampled members: %d", *approxRefs)
+	}
+	return nil
+}
+
 // Loads kmers from a HAS file.
 func loadKmers(file string, pt *ptimer.Timer) ([]*kmr.HasTuple, error) {
 	var result []*kmr.HasTuple
@@ -216,60 +353,26 @@ func loadKmersGlob(file string) ([]*kmr.HasTuple, error) {
 	return result, nil
 }
 
-// Returns the sum of square distances from each tuple to the rest.
-func sqDistances(kmers []*kmr.HasTuple) []float64 {
-	result := make([]float64, len(kmers))
-	for i, ki := range kmers {
-		for j, kj := range kmers[i+1:] {
-			d := util.JaccardDualDist(ki.Data.Samples, kj.Data.Samples, *nSamples)
-			d *= d
-			result[i] += d
-			result[j+i+1] += d
-		}
-	}
-	gnum.Mul1(result, 1.0/float64(len(kmers)-1))
-	for i := range result {
-		result[i] = math.Sqrt(result[i])
-	}
-	return result
-}
-
-// Min-hash index, for quick set lookup.
-type mhindex map[uint64][]int
-
-// Adds the given min-hashes and associates them with the given ID i.
-func (m mhindex) add(i int, mh []uint64) {
-	for _, h := range mh {
-		m[h] = append(m[h], i)
-	}
-}
-
-// Searches for ID's that share at least min min-hashes with i.
-func (m mhindex) search(i int, mh []uint64, min int) []int {
-	// TODO(amit): make the min = min(min, len)
-	cnt := map[int]int{}
-	for _, h := range mh {
-		for _, ii := range m[h] {
-			if ii <= i { // Avoid pair repetition and self.
-				continue
-			}
-			cnt[ii]++
-		}
+// Saves the members of each cluster in binary form, sorted by center kmer.
+func saveClusters(file string, clusters []cluster) error {
+	f, err := aio.Create(file)
+	if err != nil {
+		return err
 	}
-	var result []int
-	for k, v := range cnt {
-		if v >= min {
-			result = append(result, k)
+	clusters = slices.Clone(clusters)
+	slices.SortFunc(clusters, func(a, b cluster) int {
+		return a.center.Kmer.Compare(b.center.Kmer)
+	})
+	w := bnry.NewWriter(f)
+	for _, c := range clusters {
+		members := slices.Clone(c.members)
+		slices.SortFunc(members, kmr.Kmer.Compare)
+		t := &kmr.ClusterTuple{Kmer: c.center.Kmer,
+			Data: kmr.ClusterData{Members: members}}
+		if err := t.Encode(w); err != nil {
+			f.Close()
+			return err
 		}
 	}
-	return result
-}
-
-// Returns the k min-hashes for the ints in a.
-func intsMinHash(a []int, k int) []uint64 {
-	mh := minhash.New[uint64](k)
-	for _, i := range a {
-		mh.Push(util.Hash64Int(i))
-	}
-	return mh.View()
+	return f.Close()
 }
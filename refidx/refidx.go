@@ -0,0 +1,165 @@
+// Indexes the kmers of reference sequences by their positions.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/fluhus/biostuff/formats/bioiter/v2"
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/bnry"
+	"github.com/fluhus/gostuff/ptimer"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+)
+
+var (
+	inFile  = flag.String("i", "", "Input FASTA file glob pattern")
+	outFile = flag.String("o", "", "Output file")
+	bufSize = flag.Int("b", 100_000_000,
+		"Maximal number of kmer positions to hold in memory")
+	tmpDir = flag.String("tmp", "", "Directory for temporary files")
+)
+
+// A kmer with one of its positions.
+type kmerHit struct {
+	kmer kmr.Kmer
+	hit  kmr.RefHit
+}
+
+func main() {
+	util.Die(parseArgs())
+	files, err := filepath.Glob(*inFile)
+	util.Die(err)
+	if len(files) == 0 {
+		util.Die(fs.ErrNotExist)
+	}
+	util.Die(buildIndex(files))
+	fmt.Println("Done")
+}
+
+// Writes the index of the given FASTA files to the output file.
+func buildIndex(files []string) error {
+	var runs []string
+	defer func() {
+		for _, f := range runs {
+			os.Remove(f)
+		}
+	}()
+	// Spills the hits to a temporary file.
+	spill := func(hits []kmerHit) error {
+		run, err := writeRun(hits)
+		if run != "" {
+			runs = append(runs, run)
+		}
+		return err
+	}
+
+	fmt.Println("Reading", len(files), "files")
+	var hits []kmerHit
+	pt := ptimer.NewMessage("{} sequences read")
+	for _, file := range files {
+		for fa, err := range bioiter.Fasta(file) {
+			if err != nil {
+				return err
+			}
+			kmr.RefKmers(kmr.ContigName(fa.Name), fa.Sequence,
+				func(kmer kmr.Kmer, hit kmr.RefHit) {
+					hits = append(hits, kmerHit{kmer, hit})
+				})
+			if len(hits) >= *bufSize {
+				if err := spill(hits); err != nil {
+					return err
+				}
+				hits = hits[:0]
+			}
+			pt.Inc()
+		}
+	}
+	pt.Done()
+
+	fmt.Println("Writing to:", *outFile)
+	fout, err := aio.Create(*outFile)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 { // Everything fit in memory.
+		err = writeHits(fout, hits)
+	} else {
+		if len(hits) > 0 {
+			err = spill(hits)
+		}
+		if err == nil {
+			fmt.Println("Merging", len(runs), "temporary files")
+			err = kmr.MergeFiles("ref", runs, fout)
+		}
+	}
+	if err != nil {
+		fout.Close()
+		return err
+	}
+	return fout.Close()
+}
+
+// Sorts the hits by kmer and writes them as ref tuples. Hits of the same kmer
+// keep their input order.
+func writeHits(w io.Writer, hits []kmerHit) error {
+	slices.SortStableFunc(hits, func(a, b kmerHit) int {
+		return a.kmer.Compare(b.kmer)
+	})
+	bw := bnry.NewWriter(w)
+	t := &kmr.RefTuple{}
+	for i, h := range hits {
+		if i > 0 && h.kmer != t.Kmer {
+			if err := t.Encode(bw); err != nil {
+				return err
+			}
+			t.Data.Hits = t.Data.Hits[:0]
+		}
+		t.Kmer = h.kmer
+		t.Data.Hits = append(t.Data.Hits, h.hit)
+	}
+	if len(hits) > 0 {
+		return t.Encode(bw)
+	}
+	return nil
+}
+
+// Writes the hits to a temporary file. Returns the file's name.
+func writeRun(hits []kmerHit) (string, error) {
+	f, err := os.CreateTemp(*tmpDir, "refidx-*")
+	if err != nil {
+		return "", err
+	}
+	bf := bufio.NewWriter(f)
+	if err := writeHits(bf, hits); err != nil {
+		f.Close()
+		return f.Name(), err
+	}
+	if err := bf.Flush(); err != nil {
+		f.Close()
+		return f.Name(), err
+	}
+	return f.Name(), f.Close()
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *inFile == "" {
+		return fmt.Errorf("empty input path")
+	}
+	if *outFile == "" {
+		return fmt.Errorf("empty output path")
+	}
+	if *bufSize < 1 {
+		return fmt.Errorf("bad buffer size: %d", *bufSize)
+	}
+	return nil
+}
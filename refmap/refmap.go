@@ -0,0 +1,205 @@
+// Assigns kmers to the reference sequences that contain them, with no
+// alignment of reads.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"iter"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/fluhus/biostuff/formats/bioiter/v2"
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/ptimer"
+	"github.com/fluhus/gostuff/sets"
+	"github.com/fluhus/gostuff/snm"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+	"golang.org/x/exp/constraints"
+	"golang.org/x/exp/maps"
+)
+
+var (
+	kmersFile = flag.String("k", "", "Kmers file `path` (text lines, or dump with -kd)")
+	kmersDump = flag.Bool("kd", false,
+		"Kmers file is a sorted dump; kmer indexes are their positions in the dump")
+	idxFile = flag.String("r", "", "Reference index file, created by refidx")
+	fasta   = flag.String("f", "",
+		"Reference FASTA file glob `pattern`, instead of an index")
+	nameRE  = flag.String("x", "", "Name `regex` to capture from contig names")
+	outFile = flag.String("o", "", "Output file `path`")
+)
+
+// A kmer with its indexes in the kmers file.
+type listKmer struct {
+	kmer kmr.Kmer
+	idxs []int
+}
+
+func main() {
+	util.Die(parseArgs())
+	var re *regexp.Regexp
+	if *nameRE != "" {
+		var err error
+		re, err = regexp.Compile(*nameRE)
+		util.Die(err)
+	}
+
+	fmt.Println("Reading kmers")
+	kmers, err := loadKmers(*kmersFile, *kmersDump)
+	util.Die(err)
+	fmt.Println(len(kmers), "unique kmers")
+
+	geneSets := snm.NewDefaultMap(func(s string) sets.Set[int] {
+		return sets.Set[int]{}
+	})
+	add := func(contig string, k listKmer) error {
+		if re != nil {
+			mch := re.FindString(contig)
+			if mch == "" {
+				return fmt.Errorf("contig %q does not match name pattern %v",
+					contig, re)
+			}
+			contig = mch
+		}
+		geneSets.Get(contig).Add(k.idxs...)
+		return nil
+	}
+	if *idxFile != "" {
+		fmt.Println("Reading index")
+		util.Die(assignFromIndex(kmers, *idxFile, add))
+	} else {
+		fmt.Println("Reading references")
+		util.Die(assignFromFasta(kmers, *fasta, add))
+	}
+	fmt.Println("Mapped to", len(geneSets.M), "groups")
+
+	fout, err := aio.Create(*outFile)
+	util.Die(err)
+	enc := json.NewEncoder(fout)
+	for _, k := range sortedKeys(geneSets.M) {
+		j := struct {
+			Gene  string
+			Kmers []int
+		}{k, sortedKeys(geneSets.Get(k))}
+		util.Die(enc.Encode(j))
+	}
+	util.Die(fout.Close())
+	fmt.Println("Done")
+}
+
+func sortedKeys[K constraints.Ordered, V any](m map[K]V) []K {
+	return snm.Sorted(maps.Keys(m))
+}
+
+// Calls add with each kmer found in the index and each of its contigs.
+func assignFromIndex(kmers []listKmer, file string,
+	add func(string, listKmer) error) error {
+	left := func(yield func(listKmer, error) bool) {
+		for _, k := range kmers {
+			if !yield(k, nil) {
+				return
+			}
+		}
+	}
+	refs := kmr.IterTuplesFile[kmr.RefHandler](file)
+	for j, err := range kmr.Join(iter.Seq2[listKmer, error](left), refs,
+		func(k listKmer) kmr.Kmer { return k.kmer },
+		func(t *kmr.RefTuple) kmr.Kmer { return t.Kmer }, kmr.InnerJoin) {
+		if err != nil {
+			return err
+		}
+		for _, hit := range j.Right.Data.Hits {
+			if err := add(hit.Contig, j.Left); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Calls add with each kmer found in the FASTA files and each of its contigs.
+func assignFromFasta(kmers []listKmer, glob string,
+	add func(string, listKmer) error) error {
+	files, err := filepath.Glob(glob)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("found 0 files")
+	}
+	pt := ptimer.NewMessage("{} sequences read")
+	for _, file := range files {
+		for fa, err := range bioiter.Fasta(file) {
+			if err != nil {
+				return err
+			}
+			contig := kmr.ContigName(fa.Name)
+			var errAdd error
+			kmr.RefKmers(contig, fa.Sequence, func(kmer kmr.Kmer, _ kmr.RefHit) {
+				i, ok := slices.BinarySearchFunc(kmers, kmer,
+					func(a listKmer, b kmr.Kmer) int {
+						return a.kmer.Compare(b)
+					})
+				if ok && errAdd == nil {
+					errAdd = add(contig, kmers[i])
+				}
+			})
+			if errAdd != nil {
+				return errAdd
+			}
+			pt.Inc()
+		}
+	}
+	pt.Done()
+	return nil
+}
+
+// Reads kmers from a text file or a sorted dump, sorted and unique.
+// Indexes are line numbers or positions in the dump, starting from 0,
+// as in smfq.
+func loadKmers(file string, dump bool) ([]listKmer, error) {
+	kmers, err := kmr.ReadKmersList(file, dump)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]listKmer, len(kmers))
+	for i, kmer := range kmers {
+		result[i] = listKmer{kmer, []int{i}}
+	}
+	if dump {
+		return result, nil
+	}
+	slices.SortStableFunc(result, func(a, b listKmer) int {
+		return a.kmer.Compare(b.kmer)
+	})
+	// Unite repeated kmers.
+	j := 0
+	for _, k := range result {
+		if j > 0 && result[j-1].kmer == k.kmer {
+			result[j-1].idxs = append(result[j-1].idxs, k.idxs...)
+			continue
+		}
+		result[j] = k
+		j++
+	}
+	return slices.Clip(result[:j]), nil
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *kmersFile == "" {
+		return fmt.Errorf("empty kmers path")
+	}
+	if (*idxFile == "") == (*fasta == "") {
+		return fmt.Errorf("exactly one of -r and -f should be given")
+	}
+	if *outFile == "" {
+		return fmt.Errorf("empty output path")
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Coverage tracks of kmer hits along the references.
+
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"flag"
+	"fmt"
+	"slices"
+
+	"github.com/fluhus/biostuff/formats/sam"
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/sets"
+	"github.com/fluhus/kwas/kmr/v2"
+)
+
+var (
+	covFile = flag.String("cov", "",
+		"Optional output `path` of a coverage track of kmer hits (sam)")
+	covFormat = flag.String("cf", "bedgraph",
+		"Coverage track format: bedgraph (hits per bin) or bed "+
+			"(name is distinct kmers, score is hits up to 1000)")
+	binSize = flag.Int("b", 100, "Coverage track bin size, in reference bases")
+)
+
+// A coverage bin on a reference.
+type covKey struct {
+	rname string
+	bin   int
+}
+
+// Kmer hits in a coverage bin.
+type covBin struct {
+	hits  int
+	kmers sets.Set[int]
+}
+
+// Coverage tracks, by reference and bin.
+type coverage map[covKey]*covBin
+
+// Adds a hit of the given kmer index at a 0-based reference position.
+func (c coverage) add(rname string, pos int, idx int) {
+	key := covKey{rname, pos / *binSize}
+	b := c[key]
+	if b == nil {
+		b = &covBin{kmers: sets.Set[int]{}}
+		c[key] = b
+	}
+	b.hits++
+	b.kmers.Add(idx)
+}
+
+// Writes the coverage tracks in the given format, sorted by reference and
+// position.
+func (c coverage) write(file string, format string) error {
+	keys := make([]covKey, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b covKey) int {
+		return cmp.Or(cmp.Compare(a.rname, b.rname), cmp.Compare(a.bin, b.bin))
+	})
+
+	f, err := aio.Create(file)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, k := range keys {
+		b := c[k]
+		start := k.bin * *binSize
+		end := start + *binSize
+		if format == "bed" {
+			_, err = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", k.rname, start, end,
+				len(b.kmers), min(b.hits, 1000))
+		} else {
+			_, err = fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", k.rname, start, end,
+				b.hits)
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Returns the 0-based reference position of each base of a read, or -1 for
+// bases that are not aligned (inserted or clipped). Returns nil for
+// unmapped reads and for CIGARs that are missing, malformed or do not
+// cover the read's sequence, so that they are left out of the coverage.
+func refPositions(sm *sam.SAM) []int {
+	if sm.Flag&0x4 != 0 || sm.Pos < 1 || sm.Cigar == "*" {
+		return nil
+	}
+	result := make([]int, 0, len(sm.Seq))
+	ref := sm.Pos - 1
+	err := iterCigar(sm.Cigar, func(m int, op byte) {
+		switch op {
+		case 'M', '=', 'X':
+			for range m {
+				result = append(result, ref)
+				ref++
+			}
+		case 'I', 'S':
+			for range m {
+				result = append(result, -1)
+			}
+		case 'D', 'N':
+			ref += m
+		}
+	})
+	if err != nil || len(result) != len(sm.Seq) {
+		return nil
+	}
+	return result
+}
+
+// Returns the reference position of the kmer that starts at read offset i,
+// which is that of its first aligned base, or -1 if none of its bases are
+// aligned.
+func kmerRefPos(refs []int, i int) int {
+	for _, p := range refs[i : i+kmr.K] {
+		if p != -1 {
+			return p
+		}
+	}
+	return -1
+}
+
+// Checks the coverage flags.
+func checkCoverage() error {
+	if *covFile == "" {
+		return nil
+	}
+	if *isDiamond {
+		return fmt.Errorf("coverage tracks are not supported for diamond input")
+	}
+	if *covFormat != "bed" && *covFormat != "bedgraph" {
+		return fmt.Errorf("bad coverage format: %q, want bed or bedgraph",
+			*covFormat)
+	}
+	if *binSize < 1 {
+		return fmt.Errorf("bad bin size: %d", *binSize)
+	}
+	return nil
+}
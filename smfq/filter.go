@@ -0,0 +1,119 @@
+// Alignment filters.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/fluhus/biostuff/formats/sam"
+)
+
+var (
+	minMapq = flag.Int("q", 30, "Minimal MAPQ (sam)")
+	reqFlag = flag.Int("f", 0,
+		"Only use alignments with all of these SAM flag bits (sam)")
+	excFlag = flag.Int("F", 0,
+		"Skip alignments with any of these SAM flag bits (sam), "+
+			"0xd04 skips unmapped, secondary, duplicate and supplementary")
+	minLen = flag.Int("l", 0,
+		"Minimal number of aligned bases (M, = and X in CIGAR) (sam)")
+	maxNM = flag.Int("nm", -1,
+		"Maximal edit distance (NM tag), negative for no limit (sam); "+
+			"alignments without an NM tag are skipped")
+	minIdentity = flag.Float64("id", 0,
+		"Minimal percent identity (diamond, needs a pident column)")
+	maxEvalue = flag.Float64("e", 0,
+		"Maximal e-value, 0 for no limit (diamond, needs an evalue column)")
+)
+
+// Returns whether a SAM alignment passes the filters.
+func passesSAM(sm *sam.SAM) (bool, error) {
+	if sm.Rname == "*" || sm.Mapq < *minMapq {
+		return false, nil
+	}
+	if sm.Flag&*reqFlag != *reqFlag || sm.Flag&*excFlag != 0 {
+		return false, nil
+	}
+	if *minLen > 0 {
+		n, err := alignedLength(sm.Cigar)
+		if err != nil {
+			return false, err
+		}
+		if n < *minLen {
+			return false, nil
+		}
+	}
+	if *maxNM >= 0 {
+		nm, ok := sm.Tags["NM"].(int)
+		if !ok || nm > *maxNM {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Returns whether a diamond alignment passes the filters.
+func passesDiamond(d diamondLine) bool {
+	return d.pident >= *minIdentity && (*maxEvalue == 0 || d.evalue <= *maxEvalue)
+}
+
+// Returns the number of aligned bases in a CIGAR string, which is the total
+// length of its M, = and X operations.
+func alignedLength(cigar string) (int, error) {
+	result := 0
+	err := iterCigar(cigar, func(n int, op byte) {
+		if op == 'M' || op == '=' || op == 'X' {
+			result += n
+		}
+	})
+	return result, err
+}
+
+// Calls fn on each operation of a CIGAR string.
+func iterCigar(cigar string, fn func(n int, op byte)) error {
+	if cigar == "*" {
+		return nil
+	}
+	start := 0
+	for i := 0; i < len(cigar); i++ {
+		c := cigar[i]
+		if c >= '0' && c <= '9' {
+			continue
+		}
+		n, err := strconv.Atoi(cigar[start:i])
+		if err != nil || n < 0 {
+			return fmt.Errorf("bad CIGAR: %q", cigar)
+		}
+		switch c {
+		case 'M', 'I', 'D', 'N', 'S', 'H', 'P', '=', 'X':
+		default:
+			return fmt.Errorf("bad CIGAR operation %q in: %q", c, cigar)
+		}
+		fn(n, c)
+		start = i + 1
+	}
+	if start != len(cigar) {
+		return fmt.Errorf("bad CIGAR: %q", cigar)
+	}
+	return nil
+}
+
+// Checks the filter flags.
+func checkFilters() error {
+	if *minMapq < 0 {
+		return fmt.Errorf("bad minimal MAPQ: %d", *minMapq)
+	}
+	if *reqFlag&*excFlag != 0 {
+		return fmt.Errorf("required and excluded flags overlap: %#x",
+			*reqFlag&*excFlag)
+	}
+	if *minIdentity < 0 || *minIdentity > 100 {
+		return fmt.Errorf("bad minimal identity: %v, want 0-100", *minIdentity)
+	}
+	if *maxEvalue < 0 {
+		return fmt.Errorf("bad maximal e-value: %v", *maxEvalue)
+	}
+	return nil
+}
@@ -9,8 +9,12 @@ import (
 	"fmt"
 	"io"
 	"iter"
+	"os"
 	"regexp"
 	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/fluhus/biostuff/formats/bioiter/v2"
 	"github.com/fluhus/biostuff/formats/sam"
@@ -20,6 +24,7 @@ import (
 	"github.com/fluhus/gostuff/ptimer"
 	"github.com/fluhus/gostuff/sets"
 	"github.com/fluhus/gostuff/snm"
+	"github.com/fluhus/kwas/bam"
 	"github.com/fluhus/kwas/kmr/v2"
 	"github.com/fluhus/kwas/util"
 	"golang.org/x/exp/constraints"
@@ -27,17 +32,24 @@ import (
 )
 
 var (
-	samFile   = flag.String("i", "", "Input file `path` (sam or diamond)")
-	kmersFile = flag.String("k", "", "Kmers file `path`")
-	outFile   = flag.String("o", "", "Output file `path`")
-	nameRE    = flag.String("x", "", "Name `regex` to capture")
-	nThreads  = flag.Int("t", 1, "Number of threads")
-	isDiamond = flag.Bool("d", false, "Input is a diamond file")
+	samFile     = flag.String("i", "", "Input file `path` (sam, bam or diamond)")
+	kmersFile   = flag.String("k", "", "Kmers file `path`")
+	outFile     = flag.String("o", "", "Output file `path`")
+	nameRE      = flag.String("x", "", "Name `regex` to capture")
+	nThreads    = flag.Int("t", 1, "Number of threads")
+	isDiamond   = flag.Bool("d", false, "Input is a diamond file")
+	diamondCols = flag.String("dc", "qseqid,sseqid,qseq",
+		"Comma-separated columns of the diamond file, as given to its "+
+			"--outfmt 6; qseqid, sseqid, qseq, pident and evalue are used")
 )
 
 func main() {
 	debug.SetGCPercent(33)
 	flag.Parse()
+	util.Die(checkFilters())
+	util.Die(checkCoverage())
+	cols, err := parseDiamondCols(*diamondCols)
+	util.Die(err)
 
 	var re *regexp.Regexp
 	if *nameRE != "" {
@@ -50,10 +62,10 @@ func main() {
 		return sets.Set[int]{}
 	})
 
-	const nk = 25000000 // Batch size.
+	cov := coverage{}
 
 	round := 0
-	for wl, err := range iterKmersBatch(*kmersFile, nk) {
+	for find, err := range iterFinders(*kmersFile) {
 		util.Die(err)
 		round++
 		if *isDiamond {
@@ -66,8 +78,11 @@ func main() {
 				idx  int
 			}
 			err = ppln.NonSerial(*nThreads,
-				iterDiamondFile(*samFile),
+				iterDiamondFile(*samFile, cols),
 				func(a diamondLine, g int) ([]geneidx, error) {
+					if !passesDiamond(a) {
+						return nil, nil
+					}
 					if re != nil {
 						mch := re.FindString(a.rid)
 						if mch == "" {
@@ -77,14 +92,10 @@ func main() {
 						}
 						a.rid = mch
 					}
-					seq := []byte(a.qseq)
 					var result []geneidx
-					for i := range seq[kmr.K-1:] {
-						kmer := seq[i : i+kmr.K]
-						if idx, ok := wl[*(*kmert)(kmer)]; ok {
-							result = append(result, geneidx{a.rid, idx})
-						}
-					}
+					find([]byte(a.qseq), func(idx, _ int) {
+						result = append(result, geneidx{a.rid, idx})
+					})
 					return result, nil
 				},
 				func(a []geneidx) error {
@@ -103,15 +114,27 @@ func main() {
 				return fmt.Sprint(i, " reads")
 			})
 			type geneidx struct {
-				gene string
-				idx  int
+				gene  string
+				idx   int
+				rname string // Original rname, for coverage.
+				pos   int    // Reference position, -1 if unaligned.
 			}
 			err = ppln.NonSerial(*nThreads,
-				bioiter.SAM(*samFile),
+				iterSAMFile(*samFile, *nThreads),
 				func(sm *sam.SAM, g int) ([]geneidx, error) {
-					if sm.Rname == "*" || sm.Mapq < 30 {
+					if ok, err := passesSAM(sm); !ok || err != nil {
+						return nil, err
+					}
+					if len(sm.Seq) < kmr.K {
 						return nil, nil
 					}
+					// SEQ is stored in the reference's orientation, so offsets
+					// map directly to reference positions.
+					var refs []int
+					if *covFile != "" {
+						refs = refPositions(sm)
+					}
+					rname := sm.Rname
 					if re != nil {
 						mch := re.FindString(sm.Rname)
 						if mch == "" {
@@ -121,19 +144,23 @@ func main() {
 						}
 						sm.Rname = mch
 					}
-					seq := []byte(sm.Seq)
 					var result []geneidx
-					for i := range seq[kmr.K-1:] {
-						kmer := seq[i : i+kmr.K]
-						if idx, ok := wl[*(*kmert)(kmer)]; ok {
-							result = append(result, geneidx{sm.Rname, idx})
+					find([]byte(sm.Seq), func(idx, i int) {
+						pos := -1
+						if refs != nil {
+							pos = kmerRefPos(refs, i)
 						}
-					}
+						result = append(result,
+							geneidx{sm.Rname, idx, rname, pos})
+					})
 					return result, nil
 				},
 				func(a []geneidx) error {
 					for _, ig := range a {
 						geneSets.Get(ig.gene).Add(ig.idx)
+						if ig.pos != -1 {
+							cov.add(ig.rname, ig.pos, ig.idx)
+						}
 					}
 					pt.Inc()
 					return nil
@@ -144,6 +171,10 @@ func main() {
 		}
 	}
 	fmt.Println("Mapped to", len(geneSets.M), "groups")
+	if *covFile != "" {
+		fmt.Println("Writing coverage to:", *covFile)
+		util.Die(cov.write(*covFile, *covFormat))
+	}
 	fout, err := aio.Create(*outFile)
 	util.Die(err)
 	enc := json.NewEncoder(fout)
@@ -206,11 +237,78 @@ func sortedKeys[K constraints.Ordered, V any](m map[K]V) []K {
 	return snm.Sorted(maps.Keys(m))
 }
 
+// Iterates over the alignments in a SAM or a BAM file, detected by the file's
+// first bytes. BGZF-compressed input is decompressed using n goroutines.
+func iterSAMFile(file string, n int) iter.Seq2[*sam.SAM, error] {
+	return func(yield func(*sam.SAM, error) bool) {
+		f, err := os.Open(file)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer f.Close()
+		header, _ := bufio.NewReader(f).Peek(18)
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		seq := bioiter.SAM(file)
+		if bam.IsBGZF(header) {
+			z := bam.NewBGZFReader(f, n)
+			defer z.Close()
+			r := bufio.NewReader(z)
+			if magic, _ := r.Peek(4); bam.IsBAM(magic) {
+				seq = bam.IterReader(r)
+			} else { // Compressed SAM.
+				seq = bioiter.SAMReader(r)
+			}
+		}
+		for sm, err := range seq {
+			if !yield(sm, err) {
+				return
+			}
+		}
+	}
+}
+
 type diamondLine struct {
 	qid, rid, qseq string
+	pident, evalue float64
 }
 
-func iterDiamondFile(file string) iter.Seq2[diamondLine, error] {
+// Indexes of the used columns of a diamond file, -1 if missing.
+type diamondColumns struct {
+	qid, rid, qseq, pident, evalue int
+	n                              int // Total number of columns.
+}
+
+// Parses a comma-separated list of diamond columns, as given to --outfmt.
+func parseDiamondCols(s string) (diamondColumns, error) {
+	names := strings.Split(s, ",")
+	index := func(name string) int {
+		return slices.Index(names, name)
+	}
+	result := diamondColumns{index("qseqid"), index("sseqid"), index("qseq"),
+		index("pident"), index("evalue"), len(names)}
+	if result.qid == -1 || result.rid == -1 || result.qseq == -1 {
+		return diamondColumns{}, fmt.Errorf(
+			"diamond columns should include qseqid, sseqid and qseq: %q", s)
+	}
+	if *minIdentity > 0 && result.pident == -1 {
+		return diamondColumns{}, fmt.Errorf(
+			"identity filter needs a pident column")
+	}
+	if *maxEvalue > 0 && result.evalue == -1 {
+		return diamondColumns{}, fmt.Errorf(
+			"e-value filter needs an evalue column")
+	}
+	return result, nil
+}
+
+// Iterates over the lines of a diamond file, with the given column indexes.
+func iterDiamondFile(file string, cols diamondColumns,
+) iter.Seq2[diamondLine, error] {
 	return func(yield func(diamondLine, error) bool) {
 		f, err := aio.Open(file)
 		if err != nil {
@@ -230,11 +328,28 @@ func iterDiamondFile(file string) iter.Seq2[diamondLine, error] {
 				yield(diamondLine{}, err)
 				return
 			}
-			if len(line) != 3 {
+			if len(line) != cols.n {
 				yield(diamondLine{}, fmt.Errorf("bad number of fields in: %v", line))
 				return
 			}
-			if !yield(diamondLine{line[0], line[1], line[2]}, nil) {
+			d := diamondLine{
+				qid:  line[cols.qid],
+				rid:  line[cols.rid],
+				qseq: line[cols.qseq],
+			}
+			if i := cols.pident; i != -1 {
+				if d.pident, err = strconv.ParseFloat(line[i], 64); err != nil {
+					yield(diamondLine{}, fmt.Errorf("bad pident in: %v", line))
+					return
+				}
+			}
+			if i := cols.evalue; i != -1 {
+				if d.evalue, err = strconv.ParseFloat(line[i], 64); err != nil {
+					yield(diamondLine{}, fmt.Errorf("bad evalue in: %v", line))
+					return
+				}
+			}
+			if !yield(d, nil) {
 				return
 			}
 		}
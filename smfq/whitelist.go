@@ -0,0 +1,73 @@
+// Kmer whitelists.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"iter"
+	"slices"
+
+	"github.com/fluhus/kwas/kmr/v2"
+)
+
+var kmersDump = flag.Bool("kd", false,
+	"Kmers file is a sorted dump, looked up in a single pass; "+
+		"kmer indexes are their positions in the dump")
+
+// Calls fn with the whitelist index and the start position of each
+// whitelisted kmer in seq.
+type kmerFinder func(seq []byte, fn func(idx, pos int))
+
+// Iterates over finders for the kmers file, each covering a part of the
+// whitelist. Every finder needs a pass over the input.
+func iterFinders(file string) iter.Seq2[kmerFinder, error] {
+	if *kmersDump {
+		return func(yield func(kmerFinder, error) bool) {
+			wl, err := kmr.ReadKmersList(file, true)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			fmt.Println(len(wl), "kmers")
+			yield(whitelist(wl).find, nil)
+		}
+	}
+	const nk = 25000000 // Batch size.
+	return func(yield func(kmerFinder, error) bool) {
+		for wl, err := range iterKmersBatch(file, nk) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			find := func(seq []byte, fn func(idx, pos int)) {
+				if len(seq) < kmr.K {
+					return
+				}
+				for i := range seq[kmr.K-1:] {
+					kmer := seq[i : i+kmr.K]
+					if idx, ok := wl[*(*kmert)(kmer)]; ok {
+						fn(idx, i)
+					}
+				}
+			}
+			if !yield(find, nil) {
+				return
+			}
+		}
+	}
+}
+
+// A sorted list of canonical 2-bit kmers, taking 5 bytes per kmer.
+type whitelist []kmr.Kmer
+
+// Finds the whitelisted kmers in seq, by their canonical forms. Kmers with
+// bases other than ACGT are skipped.
+func (w whitelist) find(seq []byte, fn func(idx, pos int)) {
+	kmr.CanonicalKmers(seq, func(kmer kmr.Kmer, pos int, _ bool) {
+		idx, ok := slices.BinarySearchFunc(w, kmer, kmr.Kmer.Compare)
+		if ok {
+			fn(idx, pos)
+		}
+	})
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/fluhus/gostuff/jio"
 	"github.com/fluhus/gostuff/ptimer"
 	"github.com/fluhus/gostuff/sets"
-	"github.com/fluhus/gostuff/snm"
 	"github.com/fluhus/kwas/gofisher"
 	"github.com/fluhus/kwas/util"
 	"golang.org/x/exp/maps"
@@ -18,11 +17,24 @@ import (
 var (
 	sigf  = flag.String("s", "", "Input significant file")
 	nsigf = flag.String("n", "", "Input nonsignificant file")
-	outf  = flag.String("o", "", "Output file")
+	outf  = flag.String("o", "", "Output TSV file")
+	side  = flag.String("t", "greater",
+		"Alternative hypothesis: greater (enrichment), less (depletion) "+
+			"or two (two-sided)")
+	correction = flag.String("c", "bonferroni",
+		"Multiple testing correction: bonferroni or bh")
+	alpha    = flag.Float64("a", 0.05, "Significance threshold for q-values")
+	minCount = flag.Int("m", 1,
+		"Minimal number of kmers (significant and nonsignificant) per "+
+			"reference name for testing")
 )
 
 func main() {
-	flag.Parse()
+	util.Die(parseArgs())
+	alt, err := gofisher.ParseAlternative(*side)
+	util.Die(err)
+	correct, err := gofisher.Correction(*correction)
+	util.Die(err)
 	fmt.Println("Loading significant counts:", *sigf)
 	pt := ptimer.New()
 	sigCounts, sigSum, err := loadGeneKmers(*sigf)
@@ -34,48 +46,75 @@ func main() {
 	pt.Done()
 	util.Die(err)
 
+	found := maps.Keys(sets.Set[string]{}.Add(maps.Keys(sigCounts)...).Add(
+		maps.Keys(nsigCounts)...))
+	slices.Sort(found)
+	fmt.Println(len(found), "rnames found")
+
 	fmt.Println("Fishing")
 	pt = ptimer.New()
-	var tests []fisherResult
-	for gene, count := range sigCounts {
-		a := count
+	var results []fisherResult
+	for _, gene := range found {
+		a := sigCounts[gene]
 		b := sigSum - a
 		c := nsigCounts[gene]
 		d := nsigSum - c
-		odr, p := gofisher.Greater(a, b, c, d)
-		tests = append(tests, fisherResult{gene, odr, p})
+		if a+c < *minCount {
+			continue
+		}
+		r := gofisher.Test(a, b, c, d, alt)
+		results = append(results,
+			fisherResult{gene, a, b, c, d, r.OddsRatio, r.P, 0})
 		pt.Inc()
 	}
-	gofisher.Clear()
 	pt.Done()
 
-	tests = snm.FilterSlice(tests, func(f fisherResult) bool {
-		return f.pval <= 0.05/float64(len(tests))
-	})
-	fmt.Println(len(tests), "rnames are significant")
-	slices.SortFunc(tests, func(a, b fisherResult) int {
-		return cmp.Compare(b.odr, a.odr)
+	pvals := make([]float64, len(results))
+	for i, r := range results {
+		pvals[i] = r.pval
+	}
+	for i, q := range correct(pvals) {
+		results[i].qval = q
+	}
+	slices.SortStableFunc(results, func(a, b fisherResult) int {
+		return cmp.Compare(a.pval, b.pval)
 	})
-	fmt.Println(tests[:3])
 
-	sig := snm.SliceToSlice(tests, func(f fisherResult) string {
-		return f.rname
-	})
-	found := maps.Keys(sets.Set[string]{}.Add(maps.Keys(sigCounts)...).Add(
-		maps.Keys(nsigCounts)...))
-	slices.Sort(sig)
-	slices.Sort(found)
+	nsig := 0
+	for _, r := range results {
+		if r.qval <= *alpha {
+			nsig++
+		}
+	}
+	fmt.Println(len(results), "rnames tested,", nsig, "are significant")
+	for _, r := range results[:min(3, nsig)] {
+		fmt.Printf("%s\todds=%g\tq=%g\n", r.rname, r.odr, r.qval)
+	}
 
 	fmt.Println("Writing to:", *outf)
-	util.Die(jio.Save(*outf,
-		map[string][]string{"sig": sig, "found": found}))
+	util.Die(writeResults(*outf, results))
 }
 
-// The result of a single Fisher test, for filtering and sorting.
+// The result of a single Fisher test.
 type fisherResult struct {
-	rname string
-	odr   float64
-	pval  float64
+	rname      string
+	a, b, c, d int
+	odr        float64
+	pval       float64
+	qval       float64
+}
+
+// Writes the test results as TSV.
+func writeResults(file string, results []fisherResult) error {
+	header := []string{"rname", "a", "b", "c", "d", "odds", "p", "q"}
+	return util.WriteTSV(file, header, func(yield func([]any) bool) {
+		for _, r := range results {
+			if !yield([]any{r.rname, r.a, r.b, r.c, r.d, r.odr, r.pval,
+				r.qval}) {
+				return
+			}
+		}
+	})
 }
 
 // Loads the counts generated by smfq.
@@ -95,3 +134,18 @@ func loadGeneKmers(file string) (map[string]int, int, error) {
 	}
 	return counts, len(kmers), nil
 }
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *sigf == "" || *nsigf == "" {
+		return fmt.Errorf("empty input path")
+	}
+	if *outf == "" {
+		return fmt.Errorf("empty output path")
+	}
+	if *alpha <= 0 || *alpha > 1 {
+		return fmt.Errorf("bad significance threshold: %v", *alpha)
+	}
+	return nil
+}
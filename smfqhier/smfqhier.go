@@ -0,0 +1,260 @@
+// Runs enrichment tests on groupings of reference names, at several
+// hierarchy levels.
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/jio"
+	"github.com/fluhus/gostuff/sets"
+	"github.com/fluhus/kwas/gofisher"
+	"github.com/fluhus/kwas/util"
+	"golang.org/x/exp/maps"
+)
+
+var (
+	sigf  = flag.String("s", "", "Input significant file, from smfqmerge")
+	nsigf = flag.String("n", "", "Input nonsignificant file, from smfqmerge")
+	mapf  = flag.String("g", "", "Input TSV of reference names and their categories")
+	outf  = flag.String("o", "", "Output TSV file")
+	side  = flag.String("t", "greater",
+		"Alternative hypothesis: greater (enrichment), less (depletion) "+
+			"or two (two-sided)")
+	correction = flag.String("c", "bh",
+		"Multiple testing correction, per level: bonferroni or bh")
+	alpha    = flag.Float64("a", 0.05, "Significance threshold for q-values")
+	minCount = flag.Int("m", 1,
+		"Minimal number of kmers (significant and nonsignificant) per "+
+			"category for testing")
+)
+
+func main() {
+	util.Die(parseArgs())
+	alt, err := gofisher.ParseAlternative(*side)
+	util.Die(err)
+	correct, err := gofisher.Correction(*correction)
+	util.Die(err)
+	fmt.Println("Loading categories:", *mapf)
+	levels, cats, err := loadCategories(*mapf)
+	util.Die(err)
+	fmt.Println(len(levels), "levels:", levels)
+
+	fmt.Println("Loading significant kmers:", *sigf)
+	sig, err := loadGeneKmers(*sigf)
+	util.Die(err)
+	fmt.Println("Loading nonsignificant kmers:", *nsigf)
+	nsig, err := loadGeneKmers(*nsigf)
+	util.Die(err)
+
+	unmapped := 0
+	for _, gene := range unionKeys(sig, nsig) {
+		if _, ok := cats[gene]; !ok {
+			unmapped++
+		}
+	}
+	fmt.Println(unmapped, "reference names have no categories")
+
+	fmt.Println("Fishing")
+	var results []fisherResult
+	for i, level := range levels {
+		sigCats := groupKmers(sig, cats, i)
+		nsigCats := groupKmers(nsig, cats, i)
+		sigSum, nsigSum := countKmers(sig, cats, i), countKmers(nsig, cats, i)
+		var lresults []fisherResult
+		for _, cat := range unionKeys(sigCats, nsigCats) {
+			a := len(sigCats[cat])
+			b := sigSum - a
+			c := len(nsigCats[cat])
+			d := nsigSum - c
+			if a+c < *minCount {
+				continue
+			}
+			r := gofisher.Test(a, b, c, d, alt)
+			lresults = append(lresults,
+				fisherResult{level, cat, a, b, c, d, r.OddsRatio, r.P, 0})
+		}
+		pvals := make([]float64, len(lresults))
+		for i, r := range lresults {
+			pvals[i] = r.pval
+		}
+		for i, q := range correct(pvals) {
+			lresults[i].qval = q
+		}
+		slices.SortStableFunc(lresults, func(a, b fisherResult) int {
+			return cmp.Compare(a.pval, b.pval)
+		})
+		nsig := 0
+		for _, r := range lresults {
+			if r.qval <= *alpha {
+				nsig++
+			}
+		}
+		fmt.Printf("%s: %d categories tested, %d are significant\n",
+			level, len(lresults), nsig)
+		results = append(results, lresults...)
+	}
+
+	fmt.Println("Writing to:", *outf)
+	util.Die(writeResults(*outf, results))
+}
+
+// The result of a single Fisher test.
+type fisherResult struct {
+	level      string
+	category   string
+	a, b, c, d int
+	odr        float64
+	pval       float64
+	qval       float64
+}
+
+// Writes the test results as TSV.
+func writeResults(file string, results []fisherResult) error {
+	header := []string{"level", "category", "a", "b", "c", "d", "odds", "p",
+		"q"}
+	return util.WriteTSV(file, header, func(yield func([]any) bool) {
+		for _, r := range results {
+			if !yield([]any{r.level, r.category, r.a, r.b, r.c, r.d, r.odr,
+				r.pval, r.qval}) {
+				return
+			}
+		}
+	})
+}
+
+// Loads a TSV of reference names and their categories. The header line holds
+// a title for the name column followed by the level names. A reference name
+// may appear in several lines, for several categories; empty categories are
+// ignored.
+//
+// Returns the level names, and the sets of categories of each reference
+// name, by level.
+func loadCategories(file string) ([]string, map[string][]sets.Set[string],
+	error) {
+	f, err := aio.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comma = '\t'
+	r.Comment = '#'
+	header, err := r.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad header: %w", util.NotExpectingEOF(err))
+	}
+	if len(header) < 2 {
+		return nil, nil, fmt.Errorf("header has no levels: %q", header)
+	}
+	levels := header[1:]
+	result := map[string][]sets.Set[string]{}
+	for {
+		line, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		cats := result[line[0]]
+		if cats == nil {
+			cats = make([]sets.Set[string], len(levels))
+			for i := range cats {
+				cats[i] = sets.Set[string]{}
+			}
+			result[line[0]] = cats
+		}
+		for i, cat := range line[1:] {
+			if cat != "" {
+				cats[i].Add(cat)
+			}
+		}
+	}
+	return levels, result, nil
+}
+
+// Returns the union of the kmers of the reference names of each category at
+// the given level.
+func groupKmers(genes map[string][]int, cats map[string][]sets.Set[string],
+	level int) map[string]sets.Set[int] {
+	result := map[string]sets.Set[int]{}
+	for gene, kmers := range genes {
+		gcats, ok := cats[gene]
+		if !ok {
+			continue
+		}
+		for cat := range gcats[level] {
+			if result[cat] == nil {
+				result[cat] = sets.Set[int]{}
+			}
+			result[cat].Add(kmers...)
+		}
+	}
+	return result
+}
+
+// Returns the number of distinct kmers of the reference names that have a
+// category at the given level. Kmers of other reference names can never fall
+// in a category, so they are left out of the background.
+func countKmers(genes map[string][]int, cats map[string][]sets.Set[string],
+	level int) int {
+	kmers := sets.Set[int]{}
+	for gene, k := range genes {
+		if gcats, ok := cats[gene]; ok && len(gcats[level]) > 0 {
+			kmers.Add(k...)
+		}
+	}
+	return len(kmers)
+}
+
+// Returns the keys of both maps, sorted and unique.
+func unionKeys[V1, V2 any](a map[string]V1, b map[string]V2) []string {
+	result := maps.Keys(a)
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			result = append(result, k)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+// Loads the kmers generated by smfq, by reference name.
+func loadGeneKmers(file string) (map[string][]int, error) {
+	type geneKmers struct {
+		Gene  string
+		Kmers []int
+	}
+	result := map[string][]int{}
+	for gk, err := range jio.Iter[geneKmers](file) {
+		if err != nil {
+			return nil, err
+		}
+		result[gk.Gene] = gk.Kmers
+	}
+	return result, nil
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *sigf == "" || *nsigf == "" {
+		return fmt.Errorf("empty input path")
+	}
+	if *mapf == "" {
+		return fmt.Errorf("empty categories path")
+	}
+	if *outf == "" {
+		return fmt.Errorf("empty output path")
+	}
+	if *alpha <= 0 || *alpha > 1 {
+		return fmt.Errorf("bad significance threshold: %v", *alpha)
+	}
+	return nil
+}
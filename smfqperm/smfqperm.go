@@ -0,0 +1,417 @@
+// Runs permutation-based enrichment tests on reference names, shuffling
+// significance labels in blocks of linked kmers.
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fluhus/gostuff/jio"
+	"github.com/fluhus/gostuff/ptimer"
+	"github.com/fluhus/kwas/gofisher"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+)
+
+var (
+	sigf   = flag.String("s", "", "Input significant file, from smfqmerge")
+	nsigf  = flag.String("n", "", "Input nonsignificant file, from smfqmerge")
+	sigk   = flag.String("ks", "", "Significant kmers file, as given to smfq")
+	nsigk  = flag.String("kn", "", "Nonsignificant kmers file, as given to smfq")
+	outf   = flag.String("o", "", "Output TSV file")
+	blocks = flag.String("b", "none",
+		"Kmer blocks: none, cluster (by -j) or position (by -x and -w)")
+	clusterFile = flag.String("j", "", "Cluster JSON file, from mnzgraph -j")
+	idxFile     = flag.String("x", "", "Reference index file, from refidx")
+	window      = flag.Int("w", 1000,
+		"Window size for position blocks, in reference bases")
+	nperm      = flag.Int("p", 1000, "Number of permutations")
+	nt         = flag.Int("t", 1, "Number of threads")
+	seed       = flag.Uint64("seed", 1, "Random seed")
+	correction = flag.String("c", "bh",
+		"Multiple testing correction: bonferroni or bh")
+	minCount = flag.Int("m", 1,
+		"Minimal number of kmers (significant and nonsignificant) per "+
+			"reference name for testing")
+)
+
+func main() {
+	util.Die(parseArgs())
+	correct, err := gofisher.Correction(*correction)
+	util.Die(err)
+
+	// Kmers are numbered with the significant ones first.
+	fmt.Println("Loading kmers")
+	kmers, err := kmr.ReadKmersList(*sigk, false)
+	util.Die(err)
+	nsig := len(kmers)
+	nkmers, err := kmr.ReadKmersList(*nsigk, false)
+	util.Die(err)
+	kmers = append(kmers, nkmers...)
+	fmt.Println(nsig, "significant out of", len(kmers), "kmers")
+
+	fmt.Println("Loading reference names")
+	genes, err := loadGenes(*sigf, *nsigf, nsig, len(kmers))
+	util.Die(err)
+	genes = slices.DeleteFunc(genes, func(g gene) bool {
+		return len(g.kmers) < *minCount
+	})
+	fmt.Println(len(genes), "reference names to test")
+
+	fmt.Println("Finding blocks")
+	var blockOf []int
+	switch *blocks {
+	case "cluster":
+		blockOf, err = clusterBlocks(kmers, *clusterFile)
+	case "position":
+		blockOf, err = positionBlocks(kmers, *idxFile, *window)
+	default:
+		blockOf = make([]int, len(kmers))
+		for i := range blockOf {
+			blockOf[i] = -1
+		}
+	}
+	util.Die(err)
+	bl := newBlockLabels(blockOf, nsig)
+	fmt.Println(len(bl.blocks), "blocks in", len(bl.bySize), "sizes")
+	if bl.fixed > 0 {
+		fmt.Println(bl.fixed, "blocks have a unique size and keep their labels")
+	}
+
+	fmt.Println("Permuting")
+	counts := permute(bl, genes)
+
+	results := make([]permResult, len(genes))
+	pvals := make([]float64, len(genes))
+	for i, g := range genes {
+		results[i] = permResult{
+			rname:    g.name,
+			n:        len(g.kmers),
+			a:        g.observed,
+			expected: float64(counts[i].sum) / float64(*nperm),
+			pval:     float64(counts[i].ge+1) / float64(*nperm+1),
+		}
+		pvals[i] = results[i].pval
+	}
+	for i, q := range correct(pvals) {
+		results[i].qval = q
+	}
+	slices.SortStableFunc(results, func(a, b permResult) int {
+		return cmp.Compare(a.pval, b.pval)
+	})
+
+	fmt.Println("Writing to:", *outf)
+	util.Die(writeResults(*outf, results))
+	fmt.Println("Done")
+}
+
+// A reference name with its kmers.
+type gene struct {
+	name     string
+	kmers    []int // Kmer numbers.
+	observed int   // Number of significant kmers.
+}
+
+// Permutation counts of a single reference name.
+type permCount struct {
+	ge  int // Permutations with at least the observed significant kmers.
+	sum int // Total significant kmers over all permutations.
+}
+
+// The result of a single permutation test.
+type permResult struct {
+	rname    string
+	n        int
+	a        int
+	expected float64
+	pval     float64
+	qval     float64
+}
+
+// Significance labels of kmers, grouped in blocks that are shuffled as
+// units.
+type blockLabels struct {
+	blocks [][]int  // Kmer numbers of each block.
+	labels [][]bool // Significance labels of each block.
+	bySize [][]int  // Block numbers, grouped by block size.
+	fixed  int      // Number of blocks that are alone in their size.
+}
+
+// Groups kmers by their block numbers, -1 being a singleton block.
+// The first nsig kmers are significant.
+func newBlockLabels(blockOf []int, nsig int) *blockLabels {
+	result := &blockLabels{}
+	ids := map[int]int{}
+	for i, b := range blockOf {
+		j, ok := ids[b]
+		if b == -1 || !ok {
+			j = len(result.blocks)
+			result.blocks = append(result.blocks, nil)
+			result.labels = append(result.labels, nil)
+			if b != -1 {
+				ids[b] = j
+			}
+		}
+		result.blocks[j] = append(result.blocks[j], i)
+		result.labels[j] = append(result.labels[j], i < nsig)
+	}
+
+	sizes := map[int]int{}
+	for i, b := range result.blocks {
+		j, ok := sizes[len(b)]
+		if !ok {
+			j = len(result.bySize)
+			sizes[len(b)] = j
+			result.bySize = append(result.bySize, nil)
+		}
+		result.bySize[j] = append(result.bySize[j], i)
+	}
+	for _, group := range result.bySize {
+		if len(group) == 1 {
+			result.fixed++
+		}
+	}
+	return result
+}
+
+// Writes into dst a random assignment of labels to kmers, where blocks of the
+// same size exchange their label vectors. This keeps the number of
+// significant kmers. Blocks with a unique size keep their own labels.
+func (bl *blockLabels) shuffle(dst []bool, rng *rand.Rand) {
+	for _, group := range bl.bySize {
+		perm := rng.Perm(len(group))
+		for i, b := range group {
+			for j, kmer := range bl.blocks[b] {
+				dst[kmer] = bl.labels[group[perm[i]]][j]
+			}
+		}
+	}
+}
+
+// Runs the permutations on parallel workers. Each permutation has its own
+// random source, so the results do not depend on the number of workers.
+func permute(bl *blockLabels, genes []gene) []permCount {
+	nkmers := 0
+	for _, b := range bl.blocks {
+		nkmers += len(b)
+	}
+	perms := make(chan int, *nt)
+	go func() {
+		for i := range *nperm {
+			perms <- i
+		}
+		close(perms)
+	}()
+
+	pt := ptimer.NewMessage("{} permutations")
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result := make([]permCount, len(genes))
+	for range *nt {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counts := make([]permCount, len(genes))
+			labels := make([]bool, nkmers)
+			for i := range perms {
+				rng := rand.New(rand.NewPCG(*seed, uint64(i)))
+				bl.shuffle(labels, rng)
+				for j, g := range genes {
+					a := 0
+					for _, kmer := range g.kmers {
+						if labels[kmer] {
+							a++
+						}
+					}
+					if a >= g.observed {
+						counts[j].ge++
+					}
+					counts[j].sum += a
+				}
+				mu.Lock()
+				pt.Inc()
+				mu.Unlock()
+			}
+			mu.Lock()
+			for j, c := range counts {
+				result[j].ge += c.ge
+				result[j].sum += c.sum
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	pt.Done()
+	return result
+}
+
+// Returns block numbers of kmers by their clusters, -1 for kmers with no
+// cluster.
+func clusterBlocks(kmers []kmr.Kmer, file string) ([]int, error) {
+	var clusters [][]string
+	if err := jio.Load(file, &clusters); err != nil {
+		return nil, err
+	}
+	cluster := map[kmr.Kmer]int{}
+	for i, c := range clusters {
+		for _, kmer := range c {
+			kmr.CanonicalKmers([]byte(kmer), func(k kmr.Kmer, _ int, _ bool) {
+				cluster[k] = i
+			})
+		}
+	}
+	result := make([]int, len(kmers))
+	found := 0
+	for i, kmer := range kmers {
+		c, ok := cluster[kmer]
+		if ok {
+			found++
+		} else {
+			c = -1
+		}
+		result[i] = c
+	}
+	fmt.Println(found, "kmers found in", len(clusters), "clusters")
+	return result, nil
+}
+
+// Returns block numbers of kmers by windows of their first position on the
+// references, -1 for kmers that are not on the references.
+func positionBlocks(kmers []kmr.Kmer, file string, window int) ([]int, error) {
+	numbers := map[kmr.Kmer][]int{}
+	for i, kmer := range kmers {
+		numbers[kmer] = append(numbers[kmer], i)
+	}
+	type windowKey struct {
+		contig string
+		window int
+	}
+	windows := map[windowKey]int{}
+	result := make([]int, len(kmers))
+	for i := range result {
+		result[i] = -1
+	}
+	found := 0
+	for t, err := range kmr.IterTuplesFile[kmr.RefHandler](file) {
+		if err != nil {
+			return nil, err
+		}
+		nums, ok := numbers[t.Kmer]
+		if !ok || len(t.Data.Hits) == 0 {
+			continue
+		}
+		hit := t.Data.Hits[0]
+		key := windowKey{hit.Contig, hit.Pos / window}
+		w, ok := windows[key]
+		if !ok {
+			w = len(windows)
+			windows[key] = w
+		}
+		for _, i := range nums {
+			result[i] = w
+		}
+		found += len(nums)
+	}
+	fmt.Println(found, "kmers found in", len(windows), "windows")
+	return result, nil
+}
+
+// Loads the reference names of the significant and nonsignificant kmers,
+// with nonsignificant kmers numbered from nsig.
+func loadGenes(sigFile, nsigFile string, nsig, n int) ([]gene, error) {
+	type geneKmers struct {
+		Gene  string
+		Kmers []int
+	}
+	byName := map[string]*gene{}
+	for i, file := range []string{sigFile, nsigFile} {
+		offset := 0
+		if i == 1 {
+			offset = nsig
+		}
+		for gk, err := range jio.Iter[geneKmers](file) {
+			if err != nil {
+				return nil, err
+			}
+			g := byName[gk.Gene]
+			if g == nil {
+				g = &gene{name: gk.Gene}
+				byName[gk.Gene] = g
+			}
+			for _, k := range gk.Kmers {
+				if k+offset >= n || (i == 0 && k >= nsig) {
+					return nil, fmt.Errorf("%s: kmer index %d is out of range",
+						file, k)
+				}
+				g.kmers = append(g.kmers, k+offset)
+			}
+			if i == 0 {
+				g.observed += len(gk.Kmers)
+			}
+		}
+	}
+	var result []gene
+	for _, g := range byName {
+		result = append(result, *g)
+	}
+	slices.SortFunc(result, func(a, b gene) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return result, nil
+}
+
+// Writes the test results as TSV.
+func writeResults(file string, results []permResult) error {
+	header := []string{"rname", "kmers", "sig", "expected", "p", "q"}
+	return util.WriteTSV(file, header, func(yield func([]any) bool) {
+		for _, r := range results {
+			if !yield([]any{r.rname, r.n, r.a, r.expected, r.pval,
+				r.qval}) {
+				return
+			}
+		}
+	})
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *sigf == "" || *nsigf == "" {
+		return fmt.Errorf("empty input path")
+	}
+	if *sigk == "" || *nsigk == "" {
+		return fmt.Errorf("empty kmers path")
+	}
+	if *outf == "" {
+		return fmt.Errorf("empty output path")
+	}
+	switch *blocks {
+	case "none":
+	case "cluster":
+		if *clusterFile == "" {
+			return fmt.Errorf("cluster blocks need a cluster file")
+		}
+	case "position":
+		if *idxFile == "" {
+			return fmt.Errorf("position blocks need a reference index")
+		}
+		if *window < 1 {
+			return fmt.Errorf("bad window size: %d", *window)
+		}
+	default:
+		return fmt.Errorf("bad blocks: %q, want none, cluster or position",
+			*blocks)
+	}
+	if *nperm < 1 {
+		return fmt.Errorf("bad number of permutations: %d", *nperm)
+	}
+	if *nt < 1 {
+		return fmt.Errorf("bad number of threads: %d", *nt)
+	}
+	return nil
+}
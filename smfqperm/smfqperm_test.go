@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestShuffle_keepsCount(t *testing.T) {
+	// Singletons, two blocks of size 2 and unique blocks of sizes 3 and 4,
+	// with 4 significant kmers.
+	blockOf := []int{-1, 0, 0, 0, -1, 1, 1, 1, 1, 2, 2, 3, 3, -1}
+	nsig := 4
+	bl := newBlockLabels(blockOf, nsig)
+
+	dst := make([]bool, len(blockOf))
+	for i := range 1000 {
+		bl.shuffle(dst, rand.New(rand.NewPCG(1, uint64(i))))
+		n := 0
+		for _, label := range dst {
+			if label {
+				n++
+			}
+		}
+		if n != nsig {
+			t.Fatalf("shuffle()=%v, has %d significant labels, want %d",
+				dst, n, nsig)
+		}
+		// Blocks with a unique size keep their labels.
+		for _, group := range bl.bySize {
+			if len(group) > 1 {
+				continue
+			}
+			for j, kmer := range bl.blocks[group[0]] {
+				if dst[kmer] != bl.labels[group[0]][j] {
+					t.Fatalf("shuffle()=%v, fixed block %v changed",
+						dst, bl.blocks[group[0]])
+				}
+			}
+		}
+	}
+}
+
+func TestNewBlockLabels_fixed(t *testing.T) {
+	tests := []struct {
+		blockOf []int
+		want    int
+	}{
+		{[]int{0, 0, 0}, 1},
+		{[]int{0, 0, 0, -1}, 2},
+		{[]int{0, 1, 1, 2, 2, 2}, 3},
+		{[]int{0, 0, 1, 1, -1, -1}, 0},
+	}
+	for _, test := range tests {
+		if got := newBlockLabels(test.blockOf, 1).fixed; got != test.want {
+			t.Errorf("newBlockLabels(%v).fixed=%d, want %d",
+				test.blockOf, got, test.want)
+		}
+	}
+}
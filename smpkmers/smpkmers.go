@@ -8,7 +8,7 @@ import (
 
 	"github.com/fluhus/gostuff/aio"
 	"github.com/fluhus/gostuff/bnry"
-	"github.com/fluhus/kwas/kmr"
+	"github.com/fluhus/kwas/kmr/v2"
 	"github.com/fluhus/kwas/progress"
 	"github.com/fluhus/kwas/util"
 )
@@ -35,26 +35,24 @@ func main() {
 		pt := progress.NewTimerFunc(func(i int) string {
 			return fmt.Sprintf("%d (kept %d)", i, kept)
 		})
-		util.Die(
-			kmr.IterTuplesFiles(*fin, &kmr.HasTuple{}, func(t *kmr.HasTuple) error {
-				pt.Inc()
-				if rand.Float64() < ratio {
-					kept++
-					return t.Encode(w)
-				}
-				return nil
-			}))
+		for t, err := range kmr.IterTuplesFiles[kmr.HasHandler](*fin) {
+			util.Die(err)
+			pt.Inc()
+			if rand.Float64() < ratio {
+				kept++
+				util.Die(t.Encode(w))
+			}
+		}
 		util.Die(out.Close())
 		pt.Done()
 	} else if *n != 0 {
 		r := util.NewReservoir[*kmr.HasTuple](*n)
 		pt := progress.NewTimer()
-		util.Die(
-			kmr.IterTuplesFiles(*fin, &kmr.HasTuple{}, func(t *kmr.HasTuple) error {
-				r.Add(t.Clone())
-				pt.Inc()
-				return nil
-			}))
+		for t, err := range kmr.IterTuplesFiles[kmr.HasHandler](*fin) {
+			util.Die(err)
+			r.Add(t.Clone())
+			pt.Inc()
+		}
 		pt.Done()
 		out, err := aio.Create(*fout)
 		util.Die(err)
@@ -69,18 +67,18 @@ func main() {
 		util.Die(err)
 		w := bnry.NewWriter(out)
 		pt := progress.NewTimer()
-		util.Die(
-			kmr.IterTuplesFiles(*fin, &kmr.HasTuple{}, func(t *kmr.HasTuple) error {
-				var samples []int
-				for _, i := range t.Data.Samples {
-					if hashInt(i)%s == 0 {
-						samples = append(samples, i)
-					}
+		for t, err := range kmr.IterTuplesFiles[kmr.HasHandler](*fin) {
+			util.Die(err)
+			var samples []int
+			for _, i := range t.Data.Samples {
+				if hashInt(i)%s == 0 {
+					samples = append(samples, i)
 				}
-				t.Data.Samples = samples
-				pt.Inc()
-				return t.Encode(w)
-			}))
+			}
+			t.Data.Samples = samples
+			pt.Inc()
+			util.Die(t.Encode(w))
+		}
 		util.Die(out.Close())
 		pt.Done()
 	}
@@ -0,0 +1,280 @@
+// Finds duplicate and related samples by the similarity of their kmer sets.
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"flag"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/ppln"
+	"github.com/fluhus/gostuff/ptimer"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/lsh"
+	"github.com/fluhus/kwas/util"
+)
+
+var (
+	ff      = flag.String("f", "", "File containing input dump file names")
+	hasGlob = flag.String("s", "", "Input HAS files glob, instead of -f")
+	outFile = flag.String("o", "", "Output TSV file of similar pairs")
+	matFile = flag.String("m", "", "Optional output TSV file of all distances")
+	minSim  = flag.Float64("d", 0.9, "Minimal Jaccard similarity to report")
+	k       = flag.Int("k", 1000, "Sketch size")
+	bands   = flag.Int("b", 0,
+		"Number of sketch bands for finding pairs, 0 for the fewest that "+
+			"find pairs at -d")
+	nSamples = flag.Int("n", 0, "Number of samples in the HAS input (-s)")
+	nt       = flag.Int("t", 1, "Number of threads")
+)
+
+// Minimal probability of finding a pair with the reported similarity.
+const minRecall = 0.99
+
+func main() {
+	util.Die(parseArgs())
+
+	var sketches []*lsh.Sketch
+	var err error
+	if *ff != "" {
+		sketches, err = sketchDumps(*ff)
+	} else {
+		sketches, err = sketchHAS(*hasGlob)
+	}
+	util.Die(err)
+	fmt.Println("Sketched", len(sketches), "samples")
+
+	fmt.Printf("Finding similar pairs with %d bands, recall %.3f at %v\n",
+		*bands, bandRecall(*minSim, *k, *bands), *minSim)
+	idx := lsh.New(*k, *bands)
+	for _, s := range sketches {
+		idx.Add(s)
+	}
+	type pair struct {
+		a, b int
+		sim  float64
+	}
+	var pairs []pair
+	var pairsl sync.Mutex
+	pt := ptimer.NewMessage("{} samples done")
+	util.Die(idx.QueryAll(1, *nt, func(a int, hits []int) error {
+		var p []pair
+		for _, b := range hits {
+			if b < a { // Avoid pair repetition.
+				continue
+			}
+			if sim := sketches[a].Jaccard(sketches[b]); sim >= *minSim {
+				p = append(p, pair{a, b, sim})
+			}
+		}
+		pairsl.Lock()
+		pairs = append(pairs, p...)
+		pt.Inc()
+		pairsl.Unlock()
+		return nil
+	}))
+	pt.Done()
+	slices.SortFunc(pairs, func(x, y pair) int {
+		return cmp.Or(cmp.Compare(x.a, y.a), cmp.Compare(x.b, y.b))
+	})
+	fmt.Println("Found", len(pairs), "similar pairs")
+
+	fout, err := aio.Create(*outFile)
+	util.Die(err)
+	w := bufio.NewWriter(fout)
+	fmt.Fprintln(w, "a\tb\tjaccard")
+	for _, p := range pairs {
+		fmt.Fprintf(w, "%d\t%d\t%g\n", p.a, p.b, p.sim)
+	}
+	util.Die(w.Flush())
+	util.Die(fout.Close())
+
+	if *matFile != "" {
+		fmt.Println("Writing distance matrix")
+		util.Die(saveMatrix(*matFile, sketches))
+	}
+
+	fmt.Println("Done")
+}
+
+// Sketches each dump file in the given file list.
+func sketchDumps(ff string) ([]*lsh.Sketch, error) {
+	files, err := util.ReadLines(aio.Open(ff))
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Sketching", len(files), "dump files")
+	var result []*lsh.Sketch
+	pt := ptimer.NewMessage("{} files sketched")
+	err = ppln.Serial(*nt,
+		func(push func(string), stop func() bool) error {
+			for _, f := range files {
+				if stop() {
+					break
+				}
+				push(f)
+			}
+			return nil
+		},
+		func(file string, i, g int) (*lsh.Sketch, error) {
+			s := lsh.EmptySketch(*k)
+			for kmer, err := range kmr.IterKmersFile(file) {
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", file, err)
+				}
+				s.Add(kmerToInt(kmer))
+			}
+			return s, nil
+		},
+		func(s *lsh.Sketch) error {
+			result = append(result, s)
+			pt.Inc()
+			return nil
+		})
+	pt.Done()
+	return result, err
+}
+
+// Sketches each sample in the given HAS files.
+func sketchHAS(glob string) ([]*lsh.Sketch, error) {
+	fmt.Println("Sketching HAS files")
+	result := make([]*lsh.Sketch, *nSamples)
+	for i := range result {
+		result[i] = lsh.EmptySketch(*k)
+	}
+	pt := ptimer.NewMessage("{} kmers")
+	for t, err := range kmr.IterTuplesFiles[kmr.HasHandler](glob) {
+		if err != nil {
+			return nil, err
+		}
+		x := kmerToInt(t.Kmer)
+		for _, s := range t.Data.Samples {
+			if s < 0 || s >= len(result) {
+				return nil, fmt.Errorf("sample %d is out of range, want 0-%d",
+					s, len(result)-1)
+			}
+			result[s].Add(x)
+		}
+		pt.Inc()
+	}
+	pt.Done()
+	return result, nil
+}
+
+// Writes the distance (1-Jaccard) between every pair of samples.
+func saveMatrix(file string, sketches []*lsh.Sketch) error {
+	rows := make([][]float64, len(sketches))
+	pt := ptimer.NewMessage("{} rows")
+	err := ppln.Serial(*nt,
+		func(push func(int), stop func() bool) error {
+			for i := range sketches {
+				push(i)
+			}
+			return nil
+		},
+		func(a, i, g int) ([]float64, error) {
+			row := make([]float64, len(sketches))
+			for j := range sketches {
+				row[j] = 1 - sketches[a].Jaccard(sketches[j])
+			}
+			return row, nil
+		},
+		func(row []float64) error {
+			rows[pt.N] = row
+			pt.Inc()
+			return nil
+		})
+	pt.Done()
+	if err != nil {
+		return err
+	}
+
+	f, err := aio.Create(file)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for i := range rows {
+		fmt.Fprint(w, "\t", i)
+	}
+	fmt.Fprintln(w)
+	for i, row := range rows {
+		fmt.Fprint(w, i)
+		for _, d := range row {
+			fmt.Fprintf(w, "\t%g", d)
+		}
+		fmt.Fprintln(w)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Returns the 2-bit kmer as an integer, for sketching.
+func kmerToInt(kmer kmr.Kmer) int {
+	x := 0
+	for _, b := range kmer {
+		x = x<<8 | int(b)
+	}
+	return x
+}
+
+// Returns the probability that two sketches with the given similarity agree
+// on at least one band.
+func bandRecall(sim float64, k, bands int) float64 {
+	rows := float64(k / bands)
+	return 1 - math.Pow(1-math.Pow(sim, rows), float64(bands))
+}
+
+// Returns the fewest bands that find pairs with the given similarity with
+// probability minRecall, or k if none do.
+func chooseBands(sim float64, k int) int {
+	for b := 1; b < k; b++ {
+		if k%b == 0 && bandRecall(sim, k, b) >= minRecall {
+			return b
+		}
+	}
+	return k
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if (*ff == "") == (*hasGlob == "") {
+		return fmt.Errorf("please use either -f or -s")
+	}
+	if *outFile == "" {
+		return fmt.Errorf("empty output path")
+	}
+	if *k < 1 {
+		return fmt.Errorf("bad sketch size: %d", *k)
+	}
+	if *minSim <= 0 || *minSim > 1 {
+		return fmt.Errorf("bad similarity: %v, want 0-1", *minSim)
+	}
+	if *bands == 0 {
+		*bands = chooseBands(*minSim, *k)
+	}
+	if *bands < 1 || *k%*bands != 0 {
+		return fmt.Errorf("bad number of bands: %d, want a divisor of %d",
+			*bands, *k)
+	}
+	if r := bandRecall(*minSim, *k, *bands); r < minRecall {
+		return fmt.Errorf("%d bands find pairs with similarity %v with "+
+			"probability %.3f, want at least %v; use more bands or a "+
+			"larger sketch", *bands, *minSim, r, minRecall)
+	}
+	if *hasGlob != "" && *nSamples < 1 {
+		return fmt.Errorf("bad number of samples: %d", *nSamples)
+	}
+	if *nt < 1 {
+		return fmt.Errorf("bad number of threads: %d", *nt)
+	}
+	return nil
+}
@@ -1,16 +1,16 @@
-// Splits HAS files by minimizer.
+// Splits tuple files by minimizer.
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/fluhus/biostuff/sequtil"
-	"github.com/fluhus/gostuff/aio"
 	"github.com/fluhus/gostuff/bnry"
 	"github.com/fluhus/gostuff/ptimer"
 	"github.com/fluhus/kwas/kmr/v2"
@@ -25,8 +25,12 @@ var (
 	short   = flag.Int("n", 0, "Stop after n kmers (for debugging)")
 	k       = flag.Int("k", 8, "Minimizer length")
 	bufSize = flag.Int("b", 1<<17, "Write buffer size, higher means more RAM but faster")
+	typ     = flag.String("t", "has", "Type of input file (has, cnt, ...)")
 )
 
+// Stops the iteration after n kmers.
+var errShort = errors.New("reached kmer limit")
+
 func main() {
 	util.Die(parseArgs())
 
@@ -34,37 +38,31 @@ func main() {
 		util.Die(deleteOutputFiles())
 	}
 
-	f, err := aio.Open(*inFile)
-	util.Die(err)
-
 	ws := map[uint64]*lazy.Writer{}
 	bws := map[uint64]*bnry.Writer{}
 
 	pt := ptimer.New()
-	t := &kmr.HasTuple{}
-	for {
-		if *short > 0 && pt.N >= *short {
-			break
-		}
-		if err = t.Decode(f); err != nil {
-			break
-		}
-		mnz := minimizer(t)
-		w := bws[mnz]
-		if w == nil {
-			ww := lazy.NewWriter(strings.ReplaceAll(*outFile, "*",
-				fmt.Sprint(mnz)), *bufSize)
-			ws[mnz] = ww
-			w = bnry.NewWriter(ww)
-			bws[mnz] = w
-		}
-		err = t.Encode(w)
-		if err != nil {
-			break
-		}
-		pt.Inc()
-	}
-	if err != io.EOF {
+	err := kmr.ForEachFile(*typ, *inFile,
+		func(kmer kmr.Kmer, write func(*bnry.Writer) error) error {
+			if *short > 0 && pt.N >= *short {
+				return errShort
+			}
+			mnz := minimizer(kmer)
+			w := bws[mnz]
+			if w == nil {
+				ww := lazy.NewWriter(strings.ReplaceAll(*outFile, "*",
+					fmt.Sprint(mnz)), *bufSize)
+				ws[mnz] = ww
+				w = bnry.NewWriter(ww)
+				bws[mnz] = w
+			}
+			if err := write(w); err != nil {
+				return err
+			}
+			pt.Inc()
+			return nil
+		})
+	if err != errShort {
 		util.Die(err)
 	}
 	for _, w := range ws {
@@ -84,16 +82,20 @@ func parseArgs() error {
 	if *outFile == "" {
 		return fmt.Errorf("empty output path")
 	}
+	if !slices.Contains(kmr.Registered(), *typ) {
+		return fmt.Errorf("unsupported type: %q, want one of %v",
+			*typ, kmr.Registered())
+	}
 	if *bufSize < 4096 {
 		return fmt.Errorf("bad buffer size: %d, want at least 4096", *bufSize)
 	}
 	return nil
 }
 
-// Returns the minimizer of the tuple's kmer.
-func minimizer(tup *kmr.HasTuple) uint64 {
+// Returns the minimizer of the kmer.
+func minimizer(kmer kmr.Kmer) uint64 {
 	return kmr.Minimizer(
-		sequtil.DNAFrom2Bit(nil, tup.Kmer[:])[:kmr.K], *k)
+		sequtil.DNAFrom2Bit(nil, kmer[:])[:kmr.K], *k)
 }
 
 // Removes all the files that match the input file pattern.
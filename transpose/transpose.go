@@ -0,0 +1,66 @@
+// Converts HAS files to sample-major form and back.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fluhus/gostuff/aio"
+	"github.com/fluhus/gostuff/ptimer"
+	"github.com/fluhus/kwas/kmr/v2"
+	"github.com/fluhus/kwas/util"
+)
+
+var (
+	inFile   = flag.String("i", "", "Input file (HAS glob, or sample-major with -r)")
+	outFile  = flag.String("o", "", "Output file (sample-major, or HAS with -r)")
+	kmerFile = flag.String("k", "", "Kmer dump file, written (or read with -r)")
+	reverse  = flag.Bool("r", false, "Convert sample-major back to HAS")
+	bufSize  = flag.Int("b", 100_000_000,
+		"Number of sample-kmer pairs to hold in memory")
+	tmpDir = flag.String("tmp", "", "Directory for temporary files")
+)
+
+func main() {
+	util.Die(parseArgs())
+
+	fout, err := aio.Create(*outFile)
+	util.Die(err)
+	pt := ptimer.New()
+	if *reverse {
+		fmt.Println("Converting to HAS")
+		util.Die(kmr.UntransposeHAS(kmr.IterSampleKmersFile(*inFile),
+			kmr.IterKmersFile(*kmerFile), fout, *bufSize, *tmpDir))
+	} else {
+		fmt.Println("Converting to sample-major")
+		kout, err := aio.Create(*kmerFile)
+		util.Die(err)
+		util.Die(kmr.TransposeHAS(kmr.IterTuplesFiles[kmr.HasHandler](*inFile),
+			fout, kout, *bufSize, *tmpDir))
+		util.Die(kout.Close())
+	}
+	util.Die(fout.Close())
+	pt.Done()
+}
+
+// Parses and checks the program arguments.
+func parseArgs() error {
+	flag.Parse()
+	if *inFile == "" {
+		return fmt.Errorf("empty input path")
+	}
+	if *outFile == "" {
+		return fmt.Errorf("empty output path")
+	}
+	if *kmerFile == "" {
+		return fmt.Errorf("empty kmer file path")
+	}
+	if *bufSize < 1 {
+		return fmt.Errorf("bad buffer size: %d", *bufSize)
+	}
+	if *tmpDir == "" {
+		*tmpDir = os.TempDir()
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Distances between presence vectors, given as sorted lists of indexes.
+
+package util
+
+// HammingDist returns the fraction of positions where the presence vectors
+// of the two sorted lists disagree.
+// n is the number of possible elements (0 to n-1).
+func HammingDist(a, b []int, n int) float64 {
+	if n == 0 { // Avoid 0/0.
+		return 0
+	}
+	common := jaccardCommon(a, b)
+	return float64(len(a)+len(b)-2*common) / float64(n)
+}
+
+// R2Dist returns 1 minus the squared Pearson correlation (r²) between the
+// presence vectors of the two sorted lists, like in LD pruning.
+// n is the number of possible elements (0 to n-1).
+func R2Dist(a, b []int, n int) float64 {
+	if n == 0 { // Avoid 0/0, empty vectors are identical.
+		return 0
+	}
+	common := jaccardCommon(a, b)
+	fn := float64(n)
+	pa := float64(len(a)) / fn
+	pb := float64(len(b)) / fn
+	vara := pa * (1 - pa)
+	varb := pb * (1 - pb)
+	if vara == 0 || varb == 0 { // Constant vector, correlation is undefined.
+		if len(a) == len(b) && common == len(a) {
+			return 0
+		}
+		return 1
+	}
+	cov := float64(common)/fn - pa*pb
+	return 1 - cov*cov/vara/varb
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/fluhus/gostuff/gnum"
+)
+
+func TestHammingDist(t *testing.T) {
+	tests := []struct {
+		a    []int
+		b    []int
+		n    int
+		want float64
+	}{
+		{nil, nil, 0, 0},
+		{nil, nil, 4, 0},
+		{[]int{1}, nil, 4, 0.25},
+		{[]int{1, 2}, []int{1, 3}, 4, 0.5},
+		{[]int{0, 1, 2, 3}, []int{0, 1, 2, 3}, 4, 0},
+		{[]int{0, 1}, []int{2, 3}, 4, 1},
+	}
+	for _, test := range tests {
+		if got := HammingDist(test.a, test.b, test.n); gnum.Abs(got-test.want) > 0.00001 {
+			t.Errorf("HammingDist(%v,%v,%v)=%v, want %v",
+				test.a, test.b, test.n, got, test.want)
+		}
+	}
+}
+
+func TestR2Dist(t *testing.T) {
+	tests := []struct {
+		a    []int
+		b    []int
+		n    int
+		want float64
+	}{
+		{[]int{0, 1}, []int{0, 1}, 4, 0},
+		{[]int{0, 1}, []int{2, 3}, 4, 0},
+		{[]int{0, 1}, []int{0, 2}, 4, 1},
+		{[]int{0}, []int{0, 1}, 4, 2.0 / 3.0},
+		{nil, nil, 4, 0},
+		{nil, []int{0, 1}, 4, 1},
+		{[]int{0, 1, 2, 3}, []int{0, 1, 2, 3}, 4, 0},
+		{nil, nil, 0, 0},
+	}
+	for _, test := range tests {
+		if got := R2Dist(test.a, test.b, test.n); gnum.Abs(got-test.want) > 0.00001 {
+			t.Errorf("R2Dist(%v,%v,%v)=%v, want %v",
+				test.a, test.b, test.n, got, test.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package util
 import (
 	"fmt"
 	"slices"
+
+	"github.com/fluhus/kwas/bitmap"
 )
 
 const jaccardStrict = true
@@ -52,8 +54,18 @@ func JaccardDist(a, b []int) float64 {
 // the two sorted lists and their complements.
 // n is the number of possible elements (0 to n-1).
 func JaccardDualDist(a, b []int, n int) float64 {
-	common := jaccardCommon(a, b)
-	union := len(a) + len(b) - common
+	return jaccardDual(jaccardCommon(a, b), len(a), len(b), n)
+}
+
+// JaccardDualDistSets is like JaccardDualDist for bitmap sets.
+func JaccardDualDistSets(a, b bitmap.Set, n int) float64 {
+	return jaccardDual(a.IntersectionCount(b), a.Len(), b.Len(), n)
+}
+
+// Returns the dual Jaccard distance given the size of the intersection, the
+// sizes of the sets and the number of possible elements.
+func jaccardDual(common, na, nb, n int) float64 {
+	union := na + nb - common
 	common2 := n - union
 	union2 := n - common
 	if union == 0 || union2 == 0 { // Avoid 0/0.
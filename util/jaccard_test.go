@@ -6,6 +6,7 @@ import (
 
 	"github.com/fluhus/gostuff/gnum"
 	"github.com/fluhus/gostuff/snm"
+	"github.com/fluhus/kwas/bitmap"
 )
 
 func TestJaccard(t *testing.T) {
@@ -48,6 +49,12 @@ func TestJaccardDual(t *testing.T) {
 			t.Errorf("JaccardDualDist(%v,%v)=%v, want %v",
 				test.a, test.b, got, want)
 		}
+		got := JaccardDualDistSets(bitmap.FromSorted(test.a),
+			bitmap.FromSorted(test.b), test.n)
+		if gnum.Abs(got-want) > 0.00001 {
+			t.Errorf("JaccardDualDistSets(%v,%v)=%v, want %v",
+				test.a, test.b, got, want)
+		}
 	}
 }
 
@@ -60,3 +67,13 @@ func BenchmarkJaccardDist(b *testing.B) {
 		JaccardDist(x, y)
 	}
 }
+
+func BenchmarkJaccardDualDistSets(b *testing.B) {
+	const n = 1000
+	x := bitmap.FromSorted(snm.Sorted(rand.Perm(n * 2)[:n]))
+	y := bitmap.FromSorted(snm.Sorted(rand.Perm(n * 2)[:n]))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		JaccardDualDistSets(x, y, n*2)
+	}
+}
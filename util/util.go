@@ -6,8 +6,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"iter"
 	"math"
 	"os"
+	"strings"
 
 	"github.com/fluhus/biostuff/sequtil"
 	"github.com/fluhus/gostuff/aio"
@@ -48,6 +50,32 @@ func ReadLines(r io.ReadCloser, err error) ([]string, error) {
 	return result, nil
 }
 
+// WriteTSV writes a header and rows of tab-separated values to a file.
+// Values are formatted with %v.
+func WriteTSV(file string, header []string, rows iter.Seq[[]any]) error {
+	f, err := aio.Create(file)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for row := range rows {
+		for i, v := range row {
+			if i > 0 {
+				w.WriteByte('\t')
+			}
+			fmt.Fprint(w, v)
+		}
+		w.WriteByte('\n')
+	}
+	// Write errors are kept by the buffered writer.
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // Split splits s by sep. Slice can be recycled for performance.
 func Split(s string, sep rune, slice []string) []string {
 	slice = slice[:0]
@@ -96,6 +124,17 @@ func SplitBytes(s []byte, sep byte, slice [][]byte) [][]byte {
 // CanonicalKmers iterates over canonical k-long subsequences of seq.
 // Makes one call to ReverseComplement.
 func CanonicalKmers(seq []byte, k int, foreach func([]byte)) {
+	CanonicalKmersPos(seq, k, func(kmer []byte, _ int, _ bool) {
+		foreach(kmer)
+	})
+}
+
+// CanonicalKmersPos iterates over canonical k-long subsequences of seq,
+// with their start positions in seq and whether they are reverse complements
+// of the subsequences at those positions.
+// Makes one call to ReverseComplement.
+func CanonicalKmersPos(seq []byte, k int,
+	foreach func(kmer []byte, pos int, rc bool)) {
 	rc := sequtil.ReverseComplement(make([]byte, 0, len(seq)), seq)
 	nk := len(seq) - k + 1
 
@@ -116,9 +155,10 @@ func CanonicalKmers(seq []byte, k int, foreach func([]byte)) {
 		}
 		kmerRC := rc[len(rc)-i-k : len(rc)-i]
 		if bytes.Compare(kmer, kmerRC) == 1 {
-			kmer = kmerRC
+			foreach(kmerRC, i, true)
+		} else {
+			foreach(kmer, i, false)
 		}
-		foreach(kmer)
 	}
 }
 
@@ -1,8 +1,12 @@
 package util
 
 import (
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -24,6 +28,23 @@ func TestReadLines(t *testing.T) {
 	}
 }
 
+func TestWriteTSV(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "out.tsv")
+	rows := [][]any{{"x", 1, 0.5}, {"y", -2, 1e-20}}
+	if err := WriteTSV(file, []string{"name", "n", "p"},
+		slices.Values(rows)); err != nil {
+		t.Fatalf("WriteTSV(%v) failed: %v", rows, err)
+	}
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "name\tn\tp\nx\t1\t0.5\ny\t-2\t1e-20\n"
+	if string(got) != want {
+		t.Fatalf("WriteTSV(%v)=%q, want %q", rows, got, want)
+	}
+}
+
 func TestSplit(t *testing.T) {
 	tests := []struct {
 		input string
@@ -60,3 +81,15 @@ func TestCanonicalKmers(t *testing.T) {
 		t.Fatalf("CanonicalKmers(%q,3)=%v, want %v", input, got, want)
 	}
 }
+
+func TestCanonicalKmersPos(t *testing.T) {
+	input := []byte("ATTANGCAC")
+	want := []string{"AAT 0 true", "TAA 1 true", "GCA 5 false", "CAC 6 false"}
+	var got []string
+	CanonicalKmersPos(input, 3, func(b []byte, pos int, rc bool) {
+		got = append(got, fmt.Sprintf("%s %d %v", b, pos, rc))
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("CanonicalKmersPos(%q,3)=%v, want %v", input, got, want)
+	}
+}